@@ -5,6 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"github.com/ken5scal/gsuite_toolkit/utilities"
+	"google.golang.org/api/admin/reports/v1"
+	"os"
+	"sort"
+	"strconv"
 	"time"
 )
 
@@ -27,10 +31,10 @@ func (action *AuditAction) SetService(s services.Service) error {
 	return nil
 }
 
-// GetCreatedUserInLastMonth
-func (action *AuditAction) GetCreatedUserInLastMonth() error {
-	firstDayOfLastMonth := utilities.Last_Month.ModifyDate(time.Now())
-	if g, err := action.AuditActivitiesService.GetUserCreatedEvents(firstDayOfLastMonth); err != nil {
+// GetCreatedUsers prints users created within window. Window defaults to since the first day of last month
+func (action *AuditAction) GetCreatedUsers(window *utilities.TimeWindow) error {
+	window = defaultWindow(window)
+	if g, err := action.AuditActivitiesService.GetUserCreatedEvents(window.Since, window.Until); err != nil {
 		return err
 	} else {
 		// TODO, Wow this nest seems so unnecessary...
@@ -46,10 +50,12 @@ func (action *AuditAction) GetCreatedUserInLastMonth() error {
 	}
 }
 
-func (action *AuditAction) GetAllGrantedPrivilegesUsersInLastMonth() error {
-	firstDayOfLastMonth := utilities.Last_Month.ModifyDate(time.Now())
-	activities, err := action.AuditActivitiesService.GetPrivilegeGrantedEvents(firstDayOfLastMonth)
-	activities2, err2 := action.AuditActivitiesService.GetDelegatedPrivilegeGrantedEvents(firstDayOfLastMonth)
+// GetAllGrantedPrivilegesUsers prints users granted admin privileges within window.
+// Window defaults to since the first day of last month
+func (action *AuditAction) GetAllGrantedPrivilegesUsers(window *utilities.TimeWindow) error {
+	window = defaultWindow(window)
+	activities, err := action.AuditActivitiesService.GetPrivilegeGrantedEvents(window.Since, window.Until)
+	activities2, err2 := action.AuditActivitiesService.GetDelegatedPrivilegeGrantedEvents(window.Since, window.Until)
 	if err != nil  {
 		return err
 	} else if err2 !=nil {
@@ -67,4 +73,90 @@ func (action *AuditAction) GetAllGrantedPrivilegesUsersInLastMonth() error {
 	}
 	return nil
 
-}
\ No newline at end of file
+}
+
+// defaultWindow returns window, or the window since the first day of last month if window has no bounds
+func defaultWindow(window *utilities.TimeWindow) *utilities.TimeWindow {
+	if window == nil || window.Since.IsZero() && window.Until.IsZero() {
+		return &utilities.TimeWindow{Since: utilities.Last_Month.ModifyDate(time.Now())}
+	}
+	return window
+}
+
+// Query lists events of activities matching query, one per row.
+// Parameters of events are flattened into columns named after them, so columns vary by application and event.
+func (action *AuditAction) Query(query *services.ActivityQuery, format string) error {
+	if err := utilities.ValidateOutputFormat(format); err != nil {
+		return err
+	}
+	activities, err := action.AuditActivitiesService.QueryActivities(query)
+	if err != nil {
+		return err
+	}
+	events, params := FlattenActivities(activities)
+	if format == utilities.JSON {
+		return utilities.WriteJSON(os.Stdout, events)
+	}
+
+	header := append(append([]string{}, auditEventColumns...), params...)
+	var rows [][]string
+	for _, e := range events {
+		row := make([]string, len(header))
+		for i, column := range header {
+			row[i] = e[column]
+		}
+		rows = append(rows, row)
+	}
+	return utilities.WriteCSV(os.Stdout, header, rows)
+}
+
+// auditEventColumns are columns common to every event
+var auditEventColumns = []string{"time", "id", "application", "type", "event", "actor", "ip_address"}
+
+// FlattenActivities turns each event of activities into a map of auditEventColumns and its parameters.
+// Names of all parameters are returned in order as well.
+func FlattenActivities(activities []*admin.Activity) ([]map[string]string, []string) {
+	var events []map[string]string
+	seen := make(map[string]bool)
+	var params []string
+	for _, a := range activities {
+		for _, e := range a.Events {
+			event := map[string]string{"type": e.Type, "event": e.Name, "ip_address": a.IpAddress}
+			if a.Id != nil {
+				event["time"] = a.Id.Time
+				event["id"] = strconv.FormatInt(a.Id.UniqueQualifier, 10)
+				event["application"] = a.Id.ApplicationName
+			}
+			if a.Actor != nil {
+				event["actor"] = a.Actor.Email
+			}
+			for _, p := range e.Parameters {
+				event[p.Name] = services.ParameterValue(p)
+				if !seen[p.Name] {
+					seen[p.Name] = true
+					params = append(params, p.Name)
+				}
+			}
+			events = append(events, event)
+		}
+	}
+	sort.Strings(params)
+	return events, params
+}
+
+// SyncArchive pulls activities of applications into archive incrementally, and prints the number of newly archived ones.
+// Every application is synced even if some fail.
+func (action *AuditAction) SyncArchive(archive *services.AuditArchive, applications []string, overlap time.Duration) error {
+	if len(applications) == 0 {
+		applications = services.AuditApplications
+	}
+	result := services.NewBulkResult("sync activities of")
+	for _, application := range applications {
+		added, err := action.AuditActivitiesService.SyncArchive(archive, application, overlap)
+		if err == nil {
+			fmt.Printf("%v: archived %d activities up to %v\n", application, added, archive.HighWater(application).Format(time.RFC3339))
+		}
+		result.Add(application, err)
+	}
+	return result.Err()
+}
@@ -6,41 +6,62 @@ import (
 	"strconv"
 	"errors"
 	"github.com/ken5scal/gsuite_toolkit/services"
+	"github.com/ken5scal/gsuite_toolkit/utilities"
+	"os"
 
 )
 
 type DriveAction struct {
 	*services.DriveService
+	user     *services.UserService
+	transfer *services.DataTransferService
+	activity *services.DriveActivityService
+	audit    *services.AuditActivitiesService
 }
 
 const (
-	FolderMimeType = "application/vnd.google-apps.folder"
+	FolderMimeType      = "application/vnd.google-apps.folder"
+	FormMimeType        = "application/vnd.google-apps.form"
+	SpreadsheetMimeType = "application/vnd.google-apps.spreadsheet"
 )
 
 func InitDriveAction() *DriveAction {
 	return &DriveAction{}
 }
 
+// SetService sets service in Action.
+// UserService and DataTransferService are required to hand over files of suspended users,
+// and DriveActivityService to show history of files. AuditActivitiesService adds views to the history.
 func (a *DriveAction) SetService(s services.Service) error {
-	if _, ok := s.(*services.DriveService); !ok {
+	switch s.(type) {
+	case *services.DriveService:
+		a.DriveService = s.(*services.DriveService)
+	case *services.UserService:
+		a.user = s.(*services.UserService)
+	case *services.DataTransferService:
+		a.transfer = s.(*services.DataTransferService)
+	case *services.DriveActivityService:
+		a.activity = s.(*services.DriveActivityService)
+	case *services.AuditActivitiesService:
+		a.audit = s.(*services.AuditActivitiesService)
+	default:
 		return errors.New(fmt.Sprintf("Invalid type: %T", s))
 	}
-	a.DriveService = s.(*services.DriveService)
 	return nil
 }
 
 func (action DriveAction) SearchFoldersByName(title string) error {
-	// 本来は'Googleフォーム'で検索したいが、検索結果が帰ってこない
+	// 本来は'Googleフォーム'で検索したいが、検索結果が帰ってこない。フォームはAuditFormsでmimeTypeから検索する
 	if r, err := action.GetDriveMaterialsWithTitle(title, FolderMimeType); err !=nil {
 		return  err
 	} else {
 		for _, f := range r {
-			if len(f.Parents) > 0 {
-				parent, _ := action.GetParents(f.Parents[0])
-				fmt.Printf(parent.Name + " > ")
+			path, err := action.GetFilePath(f)
+			if err != nil {
+				return err
 			}
 
-			fmt.Print(f.Name + "\n")
+			fmt.Println(path)
 			GetPermissions(f)
 
 			if r, err = action.GetFilesWithinDir(f.Id); err !=nil {
@@ -54,17 +75,52 @@ func (action DriveAction) SearchFoldersByName(title string) error {
 	return  nil
 }
 
+// SearchFiles lists files matching query including ones in Team Drives, with their full paths
+func (action DriveAction) SearchFiles(query *services.DriveQuery, format string) error {
+	if err := utilities.ValidateOutputFormat(format); err != nil {
+		return err
+	}
+	files, err := action.GetFiles(query, "user,allTeamDrives")
+	if err != nil {
+		return err
+	}
+
+	type result struct {
+		Id           string `json:"id"`
+		Path         string `json:"path"`
+		Owner        string `json:"owner"`
+		MimeType     string `json:"mime_type"`
+		ModifiedTime string `json:"modified_time"`
+	}
+	var results []*result
+	var rows [][]string
+	for _, f := range files {
+		path, err := action.GetFilePath(f)
+		if err != nil {
+			return err
+		}
+		r := &result{f.Id, path, fileOwner(f), f.MimeType, f.ModifiedTime}
+		results = append(results, r)
+		rows = append(rows, []string{r.Id, r.Path, r.Owner, r.MimeType, r.ModifiedTime})
+	}
+
+	if format == utilities.JSON {
+		return utilities.WriteJSON(os.Stdout, results)
+	}
+	return utilities.WriteCSV(os.Stdout, []string{"id", "path", "owner", "mime_type", "modified_time"}, rows)
+}
+
 func (action DriveAction) SearchAllFolders() error {
 	if r, err := action.GetDriveMaterialsWithTitle("*", FolderMimeType); err !=nil {
 		return  err
 	} else {
 		for _, f := range r {
-			if len(f.Parents) > 0 {
-				parent, _ := action.GetParents(f.Parents[0])
-				fmt.Printf(parent.Name + " > ")
+			path, err := action.GetFilePath(f)
+			if err != nil {
+				return err
 			}
 
-			fmt.Print(f.Name + "\n")
+			fmt.Println(path)
 			GetPermissions(f)
 
 			if r, err = action.GetFilesWithinDir(f.Id); err !=nil {
@@ -0,0 +1,161 @@
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ken5scal/gsuite_toolkit/services"
+	"github.com/ken5scal/gsuite_toolkit/utilities"
+	"google.golang.org/api/admin/reports/v1"
+	"google.golang.org/api/appsactivity/v1"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FileActivity is an event on a Drive item
+type FileActivity struct {
+	Time       string   `json:"time"`
+	Actor      string   `json:"actor"`
+	Action     string   `json:"action"`
+	Additional []string `json:"additional,omitempty"`
+	TargetId   string   `json:"target_id"`
+	TargetName string   `json:"target_name"`
+	Details    []string `json:"details,omitempty"`
+}
+
+// ShowActivity lists events of a file, or of a folder and every item under it if recursive is true, from the newest.
+// Actors are shown by their names since the Apps Activity API doesn't return emails.
+// If views is true, views of the item are merged from Drive audit of Reports API, whose actors are shown by emails.
+// Views of items under a folder are not included since Reports API can't filter events by folder.
+func (action DriveAction) ShowActivity(id string, recursive, views bool, format string) error {
+	if action.activity == nil {
+		return errors.New("DriveActivityService must be set")
+	}
+	if views && action.audit == nil {
+		return errors.New("AuditActivitiesService must be set to show views")
+	}
+	if err := utilities.ValidateOutputFormat(format); err != nil {
+		return err
+	}
+
+	events, err := action.activity.GetActivities(id, recursive)
+	if err != nil {
+		return err
+	}
+	var activities []*FileActivity
+	for _, e := range events {
+		activities = append(activities, toFileActivity(e))
+	}
+	if views {
+		viewed, err := action.audit.QueryActivities(&services.ActivityQuery{
+			Application: "drive", Event: "view", Filters: "doc_id==" + id})
+		if err != nil {
+			return err
+		}
+		for _, a := range viewed {
+			activities = append(activities, viewActivities(a)...)
+		}
+		// RFC3339 times in different offsets don't sort as strings
+		sort.SliceStable(activities, func(i, j int) bool {
+			ti, _ := time.Parse(time.RFC3339, activities[i].Time)
+			tj, _ := time.Parse(time.RFC3339, activities[j].Time)
+			return ti.After(tj)
+		})
+	}
+	return writeFileActivities(format, activities)
+}
+
+// viewActivities converts view events of Drive audit into FileActivity
+func viewActivities(a *admin.Activity) []*FileActivity {
+	var activities []*FileActivity
+	for _, e := range a.Events {
+		v := &FileActivity{Action: e.Name, Actor: "unknown"}
+		if a.Id != nil {
+			v.Time = a.Id.Time
+		}
+		if a.Actor != nil && a.Actor.Email != "" {
+			v.Actor = a.Actor.Email
+		}
+		for _, p := range e.Parameters {
+			switch p.Name {
+			case "doc_id":
+				v.TargetId = services.ParameterValue(p)
+			case "doc_title":
+				v.TargetName = services.ParameterValue(p)
+			}
+		}
+		activities = append(activities, v)
+	}
+	return activities
+}
+
+func toFileActivity(e *appsactivity.Event) *FileActivity {
+	a := &FileActivity{
+		Time:       time.Unix(0, int64(e.EventTimeMillis)*int64(time.Millisecond)).Format(time.RFC3339),
+		Action:     e.PrimaryEventType,
+		Additional: e.AdditionalEventTypes,
+		Actor:      activityUser(e.User),
+	}
+	if e.Target != nil {
+		a.TargetId, a.TargetName = e.Target.Id, e.Target.Name
+	}
+	if e.Rename != nil {
+		a.Details = append(a.Details, fmt.Sprintf("renamed %q -> %q", e.Rename.OldTitle, e.Rename.NewTitle))
+	}
+	if e.Move != nil {
+		for _, p := range e.Move.RemovedParents {
+			a.Details = append(a.Details, "moved from "+p.Title)
+		}
+		for _, p := range e.Move.AddedParents {
+			a.Details = append(a.Details, "moved to "+p.Title)
+		}
+	}
+	for _, c := range e.PermissionChanges {
+		for _, p := range c.AddedPermissions {
+			a.Details = append(a.Details, "+"+activityPermission(p))
+		}
+		for _, p := range c.RemovedPermissions {
+			a.Details = append(a.Details, "-"+activityPermission(p))
+		}
+	}
+	return a
+}
+
+// activityUser names an actor. Actors are missing for some events such as those by anonymous users.
+func activityUser(u *appsactivity.User) string {
+	switch {
+	case u == nil:
+		return "unknown"
+	case u.IsDeleted:
+		return u.Name + " (deleted)"
+	}
+	return u.Name
+}
+
+// activityPermission describes a permission such as "writer:user:Taro Yamada" or "reader:anyone(with link)"
+func activityPermission(p *appsactivity.Permission) string {
+	grantee := p.Type
+	if p.Name != "" {
+		grantee += ":" + p.Name
+	} else if p.User != nil {
+		grantee += ":" + activityUser(p.User)
+	}
+	if p.WithLink {
+		grantee += "(with link)"
+	}
+	return p.Role + ":" + grantee
+}
+
+func writeFileActivities(format string, activities []*FileActivity) error {
+	if format == utilities.JSON {
+		return utilities.WriteJSON(os.Stdout, activities)
+	}
+
+	var rows [][]string
+	for _, a := range activities {
+		rows = append(rows, []string{a.Time, a.Actor, a.Action, strings.Join(a.Additional, " "), a.TargetId, a.TargetName,
+			strings.Join(a.Details, "; ")})
+	}
+	return utilities.WriteCSV(os.Stdout, []string{"time", "actor", "action", "additional", "target_id", "target_name", "details"}, rows)
+}
@@ -0,0 +1,257 @@
+package actions
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ken5scal/gsuite_toolkit/services"
+	"github.com/ken5scal/gsuite_toolkit/utilities"
+	"google.golang.org/api/drive/v3"
+	"os"
+	"strings"
+)
+
+// Kinds of exposure
+const (
+	ExposurePublic         = "public"
+	ExposureAnyoneWithLink = "anyone_with_link"
+	ExposureExternalDomain = "external_domain"
+	ExposureExternalUser   = "external_user"
+)
+
+// Exposure is a permission sharing a file beyond own domains
+type Exposure struct {
+	FileId       string `json:"file_id"`
+	Owner        string `json:"owner"`
+	Path         string `json:"path"`
+	MimeType     string `json:"mime_type"`
+	Exposure     string `json:"exposure"`
+	PermissionId string `json:"permission_id"`
+	Role         string `json:"role"`
+	Grantee      string `json:"grantee"`
+	ModifiedTime string `json:"modified_time"`
+}
+
+var exposureHeader = []string{"file_id", "owner", "path", "mime_type", "exposure", "permission_id", "role", "grantee", "modified_time"}
+
+func (e *Exposure) row() []string {
+	return []string{e.FileId, e.Owner, e.Path, e.MimeType, e.Exposure, e.PermissionId, e.Role, e.Grantee, e.ModifiedTime}
+}
+
+// ClassifyPermission returns kind of exposure of a permission, or empty string if it is within domains.
+// https://developers.google.com/drive/v3/reference/permissions
+func ClassifyPermission(p *drive.Permission, domains []string) string {
+	switch p.Type {
+	case "anyone":
+		if p.AllowFileDiscovery {
+			return ExposurePublic
+		}
+		return ExposureAnyoneWithLink
+	case "domain":
+		if !containDomain(domains, p.Domain) {
+			return ExposureExternalDomain
+		}
+	case "user", "group":
+		if at := strings.LastIndex(p.EmailAddress, "@"); at >= 0 && !containDomain(domains, p.EmailAddress[at+1:]) {
+			return ExposureExternalUser
+		}
+	}
+	return ""
+}
+
+func containDomain(domains []string, domain string) bool {
+	for _, d := range domains {
+		if strings.EqualFold(d, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// AuditExposure lists files shared publicly, to anyone with the link, or with external domains and users.
+// If impersonate is given, it audits files owned by each of owners by impersonating them.
+// Otherwise, it audits files shared to the domain and visible to the authorized user.
+// Files whose permissions are not visible to the authorized user either are counted and reported to stderr.
+// Either way, files in shared drives (Team Drives) are not audited, since neither "domain" nor "user" corpus includes them.
+// Use `drive inventory` or `drive overshared`, which walk shared drives the authorized user is a member of.
+func (action DriveAction) AuditExposure(domains, owners []string, impersonate func(email string) (*services.DriveService, error), format string) error {
+	if err := utilities.ValidateOutputFormat(format); err != nil {
+		return err
+	}
+
+	exposures, err := action.FindExposures(domains, owners, impersonate)
+	if _, partial := err.(*services.BulkResult); err != nil && !partial {
+		return err
+	}
+	if e := writeExposures(format, exposures); e != nil {
+		return e
+	}
+	return err
+}
+
+// FindExposures collects exposures as AuditExposure does.
+// If auditing some of owners fails, it returns exposures of the others with *services.BulkResult as error.
+func (action DriveAction) FindExposures(domains, owners []string, impersonate func(email string) (*services.DriveService, error)) ([]*Exposure, error) {
+	if impersonate == nil {
+		files, err := action.DriveService.GetFiles(services.NewDriveQuery().Trashed(false), "domain")
+		if err != nil {
+			return nil, err
+		}
+		uninspected, err := fillMissingPermissions(action.DriveService, files)
+		if err != nil {
+			return nil, err
+		}
+		warnUninspected(uninspected)
+		return collectExposures(action.DriveService, files, domains)
+	}
+
+	var exposures []*Exposure
+	result := services.NewBulkResult("audit drive of")
+	for _, owner := range owners {
+		e, err := auditExposureOf(owner, impersonate, domains)
+		result.Add(owner, err)
+		exposures = append(exposures, e...)
+	}
+	return exposures, result.Err()
+}
+
+func auditExposureOf(owner string, impersonate func(email string) (*services.DriveService, error), domains []string) ([]*Exposure, error) {
+	srv, err := impersonate(owner)
+	if err != nil {
+		return nil, err
+	}
+	files, err := srv.GetFiles(services.NewDriveQuery().Owner("me").Trashed(false), "user")
+	if err != nil {
+		return nil, err
+	}
+	return collectExposures(srv, files, domains)
+}
+
+// fillMissingPermissions retrieves permissions of files listed without them.
+// The domain corpus omits permissions of files the authorized user can't share.
+// It returns IDs of files whose permissions couldn't be retrieved either.
+func fillMissingPermissions(srv *services.DriveService, files []*drive.File) ([]string, error) {
+	var uninspected []string
+	for _, f := range files {
+		if f.Permissions != nil {
+			continue
+		}
+		permissions, err := srv.GetPermissions(f.Id)
+		if services.IsForbidden(err) || services.IsNotFound(err) {
+			uninspected = append(uninspected, f.Id)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		f.Permissions = permissions
+	}
+	return uninspected, nil
+}
+
+// warnUninspected reports files whose sharing couldn't be inspected, so that an audit doesn't look complete silently
+func warnUninspected(ids []string) {
+	if len(ids) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Sharing of %d files could not be inspected since their permissions are not visible to you:\n", len(ids))
+	for _, id := range ids {
+		fmt.Fprintln(os.Stderr, id)
+	}
+}
+
+// collectExposures lists exposing permissions of files, resolving their full paths by srv
+func collectExposures(srv *services.DriveService, files []*drive.File, domains []string) ([]*Exposure, error) {
+	var exposures []*Exposure
+	for _, f := range files {
+		var path string
+		for _, p := range f.Permissions {
+			kind := ClassifyPermission(p, domains)
+			if kind == "" {
+				continue
+			}
+			if path == "" {
+				var err error
+				if path, err = srv.GetFilePath(f); err != nil {
+					return nil, err
+				}
+			}
+			grantee := p.EmailAddress
+			if p.Type == "domain" {
+				grantee = p.Domain
+			}
+			exposures = append(exposures, &Exposure{
+				FileId:       f.Id,
+				Owner:        fileOwner(f),
+				Path:         path,
+				MimeType:     f.MimeType,
+				Exposure:     kind,
+				PermissionId: p.Id,
+				Role:         p.Role,
+				Grantee:      grantee,
+				ModifiedTime: f.ModifiedTime,
+			})
+		}
+	}
+	return exposures, nil
+}
+
+func fileOwner(f *drive.File) string {
+	if len(f.Owners) > 0 {
+		return f.Owners[0].EmailAddress
+	}
+	return ""
+}
+
+// LoadExposures reads exposures written by AuditExposure. Files ending with .json are read as JSON, otherwise CSV.
+func LoadExposures(path string) ([]*Exposure, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var exposures []*Exposure
+	if strings.HasSuffix(strings.ToLower(path), ".json") {
+		if err = json.NewDecoder(f).Decode(&exposures); err != nil {
+			return nil, err
+		}
+		return exposures, nil
+	}
+
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	for i, row := range rows {
+		if len(row) != len(exposureHeader) {
+			return nil, errors.New(fmt.Sprintf("Line %d of %v must have %d columns", i+1, path, len(exposureHeader)))
+		}
+		if i == 0 && row[0] == exposureHeader[0] {
+			continue
+		}
+		exposures = append(exposures, &Exposure{row[0], row[1], row[2], row[3], row[4], row[5], row[6], row[7], row[8]})
+	}
+	return exposures, nil
+}
+
+// attributes returns fields of exposure keyed by their column names, which can be used in rules.
+// Example rule: exposure == "external_user" AND role == "writer"
+func (e *Exposure) attributes() map[string]string {
+	attributes := make(map[string]string, len(exposureHeader))
+	for i, v := range e.row() {
+		attributes[exposureHeader[i]] = v
+	}
+	return attributes
+}
+
+func writeExposures(format string, exposures []*Exposure) error {
+	if format == utilities.JSON {
+		return utilities.WriteJSON(os.Stdout, exposures)
+	}
+	var rows [][]string
+	for _, e := range exposures {
+		rows = append(rows, e.row())
+	}
+	return utilities.WriteCSV(os.Stdout, exposureHeader, rows)
+}
@@ -0,0 +1,153 @@
+package actions
+
+import (
+	"github.com/ken5scal/gsuite_toolkit/services"
+	"github.com/ken5scal/gsuite_toolkit/utilities"
+	"google.golang.org/api/drive/v3"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// FormAudit is sharing of a Google Form and its response spreadsheets
+type FormAudit struct {
+	FormId            string           `json:"form_id"`
+	Owner             string           `json:"owner"`
+	Path              string           `json:"path"`
+	Sharing           SharingSummary   `json:"sharing"`
+	ExternalResponses bool             `json:"external_responses"`
+	ResponseSheets    []*ResponseSheet `json:"response_sheets"`
+}
+
+// ResponseSheet is a spreadsheet which seems to collect responses of a form
+type ResponseSheet struct {
+	Id      string         `json:"id"`
+	Path    string         `json:"path"`
+	Sharing SharingSummary `json:"sharing"`
+}
+
+// responseSheetSuffixes are appended to name of a form when its response spreadsheet is created
+var responseSheetSuffixes = []string{" (Responses)", "（回答）", " (回答)"}
+
+// AuditForms lists Google Forms with their owners, sharing and response spreadsheets.
+// Neither Drive API nor any other API available links a form to its response spreadsheet, or tells whether
+// a form is restricted to users in the domain. Therefore, spreadsheets of the same owner named as "<form> (Responses)"
+// are regarded as response spreadsheets, and a form shared with anyone is flagged as collecting external responses.
+// If impersonate is given, it audits forms owned by each of owners as AuditExposure does.
+// As AuditExposure, forms in shared drives (Team Drives) are not audited.
+func (action DriveAction) AuditForms(domains, owners []string, impersonate func(email string) (*services.DriveService, error), format string) error {
+	if err := utilities.ValidateOutputFormat(format); err != nil {
+		return err
+	}
+
+	query := services.NewDriveQuery().
+		Or(services.NewDriveQuery().MimeType(FormMimeType), services.NewDriveQuery().MimeType(SpreadsheetMimeType)).
+		Trashed(false)
+	var audits []*FormAudit
+	var err error
+	if impersonate == nil {
+		var files []*drive.File
+		if files, err = action.DriveService.GetFiles(query, "domain"); err != nil {
+			return err
+		}
+		if audits, err = auditForms(action.DriveService, files, domains); err != nil {
+			return err
+		}
+	} else {
+		result := services.NewBulkResult("audit forms of")
+		for _, owner := range owners {
+			a, err := auditFormsOf(owner, impersonate, query, domains)
+			result.Add(owner, err)
+			audits = append(audits, a...)
+		}
+		err = result.Err()
+	}
+
+	if e := writeFormAudits(format, audits); e != nil {
+		return e
+	}
+	return err
+}
+
+func auditFormsOf(owner string, impersonate func(email string) (*services.DriveService, error), query *services.DriveQuery, domains []string) ([]*FormAudit, error) {
+	srv, err := impersonate(owner)
+	if err != nil {
+		return nil, err
+	}
+	files, err := srv.GetFiles(services.NewDriveQuery().Owner("me").And(query), "user")
+	if err != nil {
+		return nil, err
+	}
+	return auditForms(srv, files, domains)
+}
+
+// auditForms pairs forms with response spreadsheets among files
+func auditForms(srv *services.DriveService, files []*drive.File, domains []string) ([]*FormAudit, error) {
+	sheets := make(map[string][]*drive.File)
+	for _, f := range files {
+		if f.MimeType == SpreadsheetMimeType {
+			sheets[fileOwner(f)+"/"+f.Name] = append(sheets[fileOwner(f)+"/"+f.Name], f)
+		}
+	}
+
+	var audits []*FormAudit
+	for _, f := range files {
+		if f.MimeType != FormMimeType {
+			continue
+		}
+		path, err := srv.GetFilePath(f)
+		if err != nil {
+			return nil, err
+		}
+		audit := &FormAudit{
+			FormId:  f.Id,
+			Owner:   fileOwner(f),
+			Path:    path,
+			Sharing: SummarizeSharing(f, domains),
+		}
+		audit.ExternalResponses = audit.Sharing.Anyone > 0
+
+		for _, suffix := range responseSheetSuffixes {
+			for _, sheet := range sheets[audit.Owner+"/"+f.Name+suffix] {
+				sheetPath, err := srv.GetFilePath(sheet)
+				if err != nil {
+					return nil, err
+				}
+				audit.ResponseSheets = append(audit.ResponseSheets,
+					&ResponseSheet{sheet.Id, sheetPath, SummarizeSharing(sheet, domains)})
+			}
+		}
+		audits = append(audits, audit)
+	}
+	return audits, nil
+}
+
+func writeFormAudits(format string, audits []*FormAudit) error {
+	if format == utilities.JSON {
+		return utilities.WriteJSON(os.Stdout, audits)
+	}
+
+	var rows [][]string
+	for _, a := range audits {
+		var sheets, sheetExposures []string
+		for _, s := range a.ResponseSheets {
+			sheets = append(sheets, s.Path)
+			if s.Sharing.Exposure == "" {
+				sheetExposures = append(sheetExposures, "none")
+			} else {
+				sheetExposures = append(sheetExposures, s.Sharing.Exposure)
+			}
+		}
+		rows = append(rows, []string{
+			a.FormId,
+			a.Owner,
+			a.Path,
+			a.Sharing.Exposure,
+			strconv.FormatBool(a.ExternalResponses),
+			strings.Join(sheets, ";"),
+			strings.Join(sheetExposures, ";"),
+		})
+	}
+	return utilities.WriteCSV(os.Stdout,
+		[]string{"form_id", "owner", "path", "exposure", "external_responses", "response_sheets", "response_sheet_exposures"}, rows)
+}
@@ -0,0 +1,262 @@
+package actions
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/ken5scal/gsuite_toolkit/services"
+	"google.golang.org/api/drive/v3"
+	"io"
+	"os"
+	"sync"
+)
+
+// InventoryItem is a line of Drive inventory
+type InventoryItem struct {
+	Id           string         `json:"id"`
+	Name         string         `json:"name"`
+	Path         string         `json:"path"`
+	Owner        string         `json:"owner"`
+	TeamDriveId  string         `json:"team_drive_id,omitempty"`
+	Size         int64          `json:"size"`
+	MimeType     string         `json:"mime_type"`
+	ModifiedTime string         `json:"modified_time"`
+	Description  string         `json:"description,omitempty"`
+	Sharing      SharingSummary `json:"sharing"`
+}
+
+// SharingSummary counts permissions of a file by grantee type.
+// Exposure is the widest exposure among them such as "public", or empty if the file is not shared beyond domains.
+type SharingSummary struct {
+	Users    int    `json:"users"`
+	Groups   int    `json:"groups"`
+	Domains  int    `json:"domains"`
+	Anyone   int    `json:"anyone"`
+	External int    `json:"external"`
+	Exposure string `json:"exposure,omitempty"`
+}
+
+// exposureSeverity orders kinds of exposure from the narrowest
+var exposureSeverity = map[string]int{
+	ExposureExternalUser:   1,
+	ExposureExternalDomain: 2,
+	ExposureAnyoneWithLink: 3,
+	ExposurePublic:         4,
+}
+
+// SummarizeSharing summarizes permissions of a file
+func SummarizeSharing(f *drive.File, domains []string) SharingSummary {
+	var s SharingSummary
+	for _, p := range f.Permissions {
+		switch p.Type {
+		case "user":
+			if p.Role != "owner" {
+				s.Users++
+			}
+		case "group":
+			s.Groups++
+		case "domain":
+			s.Domains++
+		case "anyone":
+			s.Anyone++
+		}
+		if kind := ClassifyPermission(p, domains); kind != "" {
+			if p.Type != "anyone" {
+				s.External++
+			}
+			if exposureSeverity[kind] > exposureSeverity[s.Exposure] {
+				s.Exposure = kind
+			}
+		}
+	}
+	return s
+}
+
+// WalkDrive traverses every file and folder under root, such as "root" for My Drive, and calls fn for each of them
+// with its parent folder, which is nil for root.
+// Folders are listed concurrently by at most parallel workers. fn is never called concurrently.
+// If listing some folders fails, the others are still walked and *services.BulkResult is returned.
+func (action DriveAction) WalkDrive(root string, parallel int, fn func(f, parent *drive.File, path string) error) error {
+	if parallel < 1 {
+		return errors.New("parallel must be positive")
+	}
+
+	folder, err := action.DriveService.GetFolder(root)
+	if err != nil {
+		return err
+	}
+	if err = action.fillTeamDrivePermissions([]*drive.File{folder}); err != nil {
+		return err
+	}
+	action.DriveService.CacheFolder(folder)
+	rootPath, err := action.DriveService.GetFilePath(folder)
+	if err != nil {
+		return err
+	}
+
+	// Folders waiting to be listed are queued rather than given a goroutine each,
+	// so that memory and concurrency are bounded by parallel however large the Drive is.
+	type task struct {
+		folder *drive.File
+		path   string
+	}
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex // guards queue, pending, fn, result and fnErr
+		ready   = sync.NewCond(&mu)
+		queue   []task
+		pending int // folders queued or being listed
+		fnErr   error
+		result  = services.NewBulkResult("list folder")
+	)
+	worker := func() {
+		defer wg.Done()
+		for {
+			mu.Lock()
+			for len(queue) == 0 && pending > 0 && fnErr == nil {
+				ready.Wait()
+			}
+			if len(queue) == 0 || fnErr != nil {
+				mu.Unlock()
+				return
+			}
+			// Take the latest folder so that the walk goes depth first and the queue stays short
+			t := queue[len(queue)-1]
+			queue = queue[:len(queue)-1]
+			mu.Unlock()
+
+			children, err := action.DriveService.GetChildren(t.folder.Id)
+			if err == nil {
+				err = action.fillTeamDrivePermissions(children)
+			}
+
+			mu.Lock()
+			result.Add(t.path, err)
+			for _, c := range children {
+				if fnErr != nil {
+					break
+				}
+				childPath := t.path + "/" + c.Name
+				fnErr = fn(c, t.folder, childPath)
+				if c.MimeType == FolderMimeType {
+					action.DriveService.CacheFolder(c)
+					queue = append(queue, task{c, childPath})
+					pending++
+				}
+			}
+			pending--
+			ready.Broadcast()
+			mu.Unlock()
+		}
+	}
+
+	if err = fn(folder, nil, rootPath); err != nil {
+		return err
+	}
+	queue, pending = []task{{folder, rootPath}}, 1
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go worker()
+	}
+	wg.Wait()
+
+	if fnErr != nil {
+		return fnErr
+	}
+	return result.Err()
+}
+
+// fillTeamDrivePermissions retrieves permissions of files in Team Drives, since files.list doesn't populate them
+func (action DriveAction) fillTeamDrivePermissions(files []*drive.File) error {
+	for _, f := range files {
+		if f.TeamDriveId == "" || f.Permissions != nil {
+			continue
+		}
+		permissions, err := action.DriveService.GetPermissions(f.Id)
+		if err != nil {
+			return err
+		}
+		f.Permissions = permissions
+	}
+	return nil
+}
+
+// Inventory writes every file and folder under root as JSON Lines, as soon as each folder is listed.
+// With teamDrives, every Team Drive the authorized user is a member of is also walked.
+func (action DriveAction) Inventory(w io.Writer, root string, domains []string, parallel int, teamDrives bool) error {
+	encoder := json.NewEncoder(w)
+	return action.walkInventory(root, domains, parallel, teamDrives, func(item *InventoryItem) error {
+		return encoder.Encode(item)
+	})
+}
+
+// walkInventory walks root, and Team Drives if teamDrives is true, calling fn for each file and folder
+func (action DriveAction) walkInventory(root string, domains []string, parallel int, teamDrives bool, fn func(item *InventoryItem) error) error {
+	return action.walkAll(root, teamDrives, parallel, func(f, parent *drive.File, path string) error {
+		return fn(&InventoryItem{
+			Id:           f.Id,
+			Name:         f.Name,
+			Path:         path,
+			Owner:        fileOwner(f),
+			TeamDriveId:  f.TeamDriveId,
+			Size:         f.Size,
+			MimeType:     f.MimeType,
+			ModifiedTime: f.ModifiedTime,
+			Description:  f.Description,
+			Sharing:      SummarizeSharing(f, domains),
+		})
+	})
+}
+
+// walkAll walks root, and Team Drives if teamDrives is true, collecting partial failures into a single result
+func (action DriveAction) walkAll(root string, teamDrives bool, parallel int, fn func(f, parent *drive.File, path string) error) error {
+	roots, err := action.driveRoots(root, teamDrives)
+	if err != nil {
+		return err
+	}
+	result := services.NewBulkResult("list folder")
+	for _, r := range roots {
+		err := action.WalkDrive(r, parallel, fn)
+		if partial, ok := err.(*services.BulkResult); ok {
+			result.Items = append(result.Items, partial.Items...)
+		} else if err != nil {
+			return err
+		}
+	}
+	return result.Err()
+}
+
+// driveRoots returns root, followed by IDs of all Team Drives if teamDrives is true
+func (action DriveAction) driveRoots(root string, teamDrives bool) ([]string, error) {
+	roots := []string{root}
+	if teamDrives {
+		drives, err := action.DriveService.GetTeamDrives()
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range drives {
+			roots = append(roots, d.Id)
+		}
+	}
+	return roots, nil
+}
+
+// LoadInventory reads JSON Lines written by Inventory
+func LoadInventory(path string) ([]*InventoryItem, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var items []*InventoryItem
+	decoder := json.NewDecoder(f)
+	for {
+		item := &InventoryItem{}
+		if err = decoder.Decode(item); err == io.EOF {
+			return items, nil
+		} else if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+}
@@ -0,0 +1,193 @@
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ken5scal/gsuite_toolkit/services"
+	"github.com/ken5scal/gsuite_toolkit/utilities"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Statuses of owners of orphaned files
+const (
+	OwnerSuspended = "suspended"
+	OwnerDeleted   = "deleted"
+)
+
+// OrphanedFile is a file still shared although its owner is suspended or deleted
+type OrphanedFile struct {
+	Id          string         `json:"id"`
+	Path        string         `json:"path"`
+	Owner       string         `json:"owner"`
+	OwnerStatus string         `json:"owner_status"`
+	Sharing     SharingSummary `json:"sharing"`
+}
+
+// FindOrphanedFiles lists shared files owned by suspended users, or by users of domains who no longer exist.
+// Users of every domain of the customer are considered, including secondary domains and aliases.
+// If input is given, files are read from it, which must be inventory of the whole organization such as outputs of
+// `drive inventory` run as each user and concatenated. Inventory of a single user misses files of the others.
+// Otherwise, files of each suspended user are searched in the domain corpus. Only files visible to the authorized user are found,
+// and files of deleted users are not found since they can't be searched by their owners.
+// If transferTo is given, Drive data of each suspended owner is transferred to transferTo, such as an archive owner.
+// Files of deleted users can't be transferred any more, so they are only reported.
+func (action DriveAction) FindOrphanedFiles(input string, domains []string, transferTo string, dryRun bool, format string) error {
+	if action.user == nil {
+		return errors.New("UserService must be set")
+	} else if transferTo != "" && action.transfer == nil {
+		return errors.New("DataTransferService must be set")
+	}
+	if err := utilities.ValidateOutputFormat(format); err != nil {
+		return err
+	}
+
+	users, err := action.user.GetAllEmployees()
+	if err != nil {
+		return err
+	}
+	statuses := make(map[string]string)
+	var suspended []string
+	for _, u := range users {
+		status := "active"
+		if u.Suspended {
+			status = OwnerSuspended
+			suspended = append(suspended, u.PrimaryEmail)
+		}
+		for _, email := range append([]string{u.PrimaryEmail}, append(u.Aliases, u.NonEditableAliases...)...) {
+			statuses[strings.ToLower(email)] = status
+		}
+	}
+	ownerStatus := func(owner string) string {
+		owner = strings.ToLower(owner)
+		if status, ok := statuses[owner]; ok {
+			if status == OwnerSuspended {
+				return status
+			}
+			return ""
+		} else if at := strings.LastIndex(owner, "@"); at >= 0 && containDomain(domains, owner[at+1:]) {
+			return OwnerDeleted
+		}
+		return ""
+	}
+
+	var orphans []*OrphanedFile
+	collect := func(item *InventoryItem) {
+		s := item.Sharing
+		if s.Users+s.Groups+s.Domains+s.Anyone == 0 {
+			return
+		}
+		if status := ownerStatus(item.Owner); status != "" {
+			orphans = append(orphans, &OrphanedFile{item.Id, item.Path, item.Owner, status, item.Sharing})
+		}
+	}
+
+	if input != "" {
+		items, err := LoadInventory(input)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			collect(item)
+		}
+	} else {
+		var uninspected []string
+		result := services.NewBulkResult("search files of")
+		for _, owner := range suspended {
+			items, ids, err := action.searchInventory(services.NewDriveQuery().Owner(owner).Trashed(false), domains)
+			result.Add(owner, err)
+			for _, item := range items {
+				collect(item)
+			}
+			uninspected = append(uninspected, ids...)
+		}
+		warnUninspected(uninspected)
+		err = result.Err()
+	}
+
+	if e := writeOrphanedFiles(format, orphans); e != nil {
+		return e
+	}
+	if err != nil || transferTo == "" {
+		return err
+	}
+	return action.handOver(orphans, transferTo, dryRun)
+}
+
+// searchInventory searches files matching query in the domain corpus, and summarizes them as InventoryItem.
+// It also returns IDs of files whose permissions are not visible to the authorized user, which are left out of items.
+func (action DriveAction) searchInventory(query *services.DriveQuery, domains []string) ([]*InventoryItem, []string, error) {
+	files, err := action.DriveService.GetFiles(query, "domain")
+	if err != nil {
+		return nil, nil, err
+	}
+	uninspected, err := fillMissingPermissions(action.DriveService, files)
+	if err != nil {
+		return nil, nil, err
+	}
+	var items []*InventoryItem
+	for _, f := range files {
+		if f.Permissions == nil {
+			continue
+		}
+		path, err := action.DriveService.GetFilePath(f)
+		if err != nil {
+			return nil, nil, err
+		}
+		items = append(items, &InventoryItem{
+			Id:           f.Id,
+			Name:         f.Name,
+			Path:         path,
+			Owner:        fileOwner(f),
+			Size:         f.Size,
+			MimeType:     f.MimeType,
+			ModifiedTime: f.ModifiedTime,
+			Description:  f.Description,
+			Sharing:      SummarizeSharing(f, domains),
+		})
+	}
+	return items, uninspected, nil
+}
+
+// handOver transfers Drive data of each suspended owner of orphans to transferTo
+func (action DriveAction) handOver(orphans []*OrphanedFile, transferTo string, dryRun bool) error {
+	owners := make(map[string]int)
+	for _, o := range orphans {
+		if o.OwnerStatus == OwnerSuspended {
+			owners[o.Owner]++
+		}
+	}
+	var sorted []string
+	for owner := range owners {
+		sorted = append(sorted, owner)
+	}
+	sort.Strings(sorted)
+
+	result := services.NewBulkResult("transfer drive of")
+	for _, owner := range sorted {
+		fmt.Fprintf(os.Stderr, "Transfer drive of %v (%d shared files) to %v\n", owner, owners[owner], transferTo)
+		if dryRun {
+			continue
+		}
+		_, err := action.transfer.StartTransfer(owner, transferTo, []string{services.TransferDrive})
+		result.Add(owner, err)
+	}
+	return result.Err()
+}
+
+func writeOrphanedFiles(format string, orphans []*OrphanedFile) error {
+	if format == utilities.JSON {
+		return utilities.WriteJSON(os.Stdout, orphans)
+	}
+
+	var rows [][]string
+	for _, o := range orphans {
+		exposure := o.Sharing.Exposure
+		if exposure == "" {
+			exposure = "internal"
+		}
+		rows = append(rows, []string{o.Id, o.Path, o.Owner, o.OwnerStatus, exposure})
+	}
+	return utilities.WriteCSV(os.Stdout, []string{"id", "path", "owner", "owner_status", "sharing"}, rows)
+}
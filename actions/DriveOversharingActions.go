@@ -0,0 +1,171 @@
+package actions
+
+import (
+	"github.com/ken5scal/gsuite_toolkit/services"
+	"github.com/ken5scal/gsuite_toolkit/utilities"
+	"google.golang.org/api/drive/v3"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Oversharing is a permission of a file granting access which its parent folder doesn't
+type Oversharing struct {
+	FileId     string `json:"file_id"`
+	Path       string `json:"path"`
+	Owner      string `json:"owner"`
+	Type       string `json:"type"`
+	Grantee    string `json:"grantee"`
+	Role       string `json:"role"`
+	FolderRole string `json:"folder_role"`
+	Exposure   string `json:"exposure"`
+}
+
+// roleRanks orders roles from the weakest
+var roleRanks = map[string]int{"reader": 1, "commenter": 2, "writer": 3, "organizer": 4, "owner": 5}
+
+// FindOversharing lists files granting access to people or scopes their parent folders don't.
+// With recursive, every file under root is compared with its parent by the walker, and Team Drives too if teamDrives is true.
+// Otherwise, only files directly within root are compared with root.
+// Exposures such as anyone with the link and external users are listed first.
+func (action DriveAction) FindOversharing(root string, domains []string, recursive, teamDrives bool, parallel int, format string) error {
+	if err := utilities.ValidateOutputFormat(format); err != nil {
+		return err
+	}
+
+	var findings []*Oversharing
+	compare := func(f, parent *drive.File, path string) error {
+		if parent != nil {
+			findings = append(findings, compareWithFolder(f, parent, path, domains)...)
+		}
+		return nil
+	}
+
+	var err error
+	if recursive {
+		err = action.walkAll(root, teamDrives, parallel, compare)
+	} else {
+		err = action.compareWithinFolder(root, compare)
+	}
+	if _, partial := err.(*services.BulkResult); err != nil && !partial {
+		return err
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return exposureSeverity[findings[i].Exposure] > exposureSeverity[findings[j].Exposure]
+	})
+	if e := writeOversharing(format, findings); e != nil {
+		return e
+	}
+	return err
+}
+
+// compareWithinFolder calls fn for each file directly within a folder by GetFilesWithinDir
+func (action DriveAction) compareWithinFolder(folderId string, fn func(f, parent *drive.File, path string) error) error {
+	folder, err := action.DriveService.GetFolder(folderId)
+	if err != nil {
+		return err
+	}
+	files, err := action.DriveService.GetFilesWithinDir(folderId)
+	if err != nil {
+		return err
+	}
+	if err = action.fillTeamDrivePermissions(append([]*drive.File{folder}, files...)); err != nil {
+		return err
+	}
+	folderPath, err := action.DriveService.GetFilePath(folder)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if f.Trashed {
+			continue
+		}
+		if err = fn(f, folder, folderPath+"/"+f.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compareWithFolder lists permissions of f not covered by any permission of its parent folder
+func compareWithFolder(f, parent *drive.File, path string, domains []string) []*Oversharing {
+	var findings []*Oversharing
+	for _, p := range f.Permissions {
+		if p.Role == "owner" || coveredBy(p, parent.Permissions) {
+			continue
+		}
+		grantee := p.EmailAddress
+		if p.Type == "domain" {
+			grantee = p.Domain
+		}
+		findings = append(findings, &Oversharing{
+			FileId:     f.Id,
+			Path:       path,
+			Owner:      fileOwner(f),
+			Type:       p.Type,
+			Grantee:    grantee,
+			Role:       p.Role,
+			FolderRole: sameGranteeRole(p, parent.Permissions),
+			Exposure:   ClassifyPermission(p, domains),
+		})
+	}
+	return findings
+}
+
+// coveredBy judges whether any of folder permissions grants the same or wider access than p.
+// Anyone covers every grantee, and a domain covers users and groups in the domain, as long as their roles are not weaker.
+func coveredBy(p *drive.Permission, folder []*drive.Permission) bool {
+	for _, fp := range folder {
+		if roleRanks[fp.Role] < roleRanks[p.Role] {
+			continue
+		}
+		switch fp.Type {
+		case "anyone":
+			if p.Type != "anyone" || fp.AllowFileDiscovery || !p.AllowFileDiscovery {
+				return true
+			}
+		case "domain":
+			switch p.Type {
+			case "domain":
+				if strings.EqualFold(fp.Domain, p.Domain) && (fp.AllowFileDiscovery || !p.AllowFileDiscovery) {
+					return true
+				}
+			case "user", "group":
+				if at := strings.LastIndex(p.EmailAddress, "@"); at >= 0 && strings.EqualFold(fp.Domain, p.EmailAddress[at+1:]) {
+					return true
+				}
+			}
+		case "user", "group":
+			if p.Type == fp.Type && strings.EqualFold(p.EmailAddress, fp.EmailAddress) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sameGranteeRole returns role of the same grantee in folder, or empty if the folder isn't shared with it
+func sameGranteeRole(p *drive.Permission, folder []*drive.Permission) string {
+	for _, fp := range folder {
+		if fp.Type != p.Type {
+			continue
+		}
+		if p.Type == "anyone" || strings.EqualFold(fp.EmailAddress, p.EmailAddress) && strings.EqualFold(fp.Domain, p.Domain) {
+			return fp.Role
+		}
+	}
+	return ""
+}
+
+func writeOversharing(format string, findings []*Oversharing) error {
+	if format == utilities.JSON {
+		return utilities.WriteJSON(os.Stdout, findings)
+	}
+
+	var rows [][]string
+	for _, f := range findings {
+		rows = append(rows, []string{f.FileId, f.Path, f.Owner, f.Type, f.Grantee, f.Role, f.FolderRole, f.Exposure})
+	}
+	return utilities.WriteCSV(os.Stdout, []string{"file_id", "path", "owner", "type", "grantee", "role", "folder_role", "exposure"}, rows)
+}
@@ -0,0 +1,51 @@
+package actions
+
+import (
+	"google.golang.org/api/drive/v3"
+	"testing"
+)
+
+func TestCoveredBy(t *testing.T) {
+	anyone := &drive.Permission{Type: "anyone", Role: "reader"}
+	discoverable := &drive.Permission{Type: "anyone", Role: "reader", AllowFileDiscovery: true}
+	domain := &drive.Permission{Type: "domain", Domain: "example.com", Role: "reader"}
+	domainWriter := &drive.Permission{Type: "domain", Domain: "Example.com", Role: "writer"}
+	alice := &drive.Permission{Type: "user", EmailAddress: "alice@example.com", Role: "reader"}
+	aliceWriter := &drive.Permission{Type: "user", EmailAddress: "Alice@example.com", Role: "writer"}
+	bob := &drive.Permission{Type: "user", EmailAddress: "bob@example.com", Role: "reader"}
+	group := &drive.Permission{Type: "group", EmailAddress: "alice@example.com", Role: "reader"}
+	external := &drive.Permission{Type: "user", EmailAddress: "carol@partner.com", Role: "reader"}
+
+	cases := []struct {
+		name   string
+		p      *drive.Permission
+		folder []*drive.Permission
+		want   bool
+	}{
+		{"unshared folder", alice, nil, false},
+		{"same user", alice, []*drive.Permission{alice}, true},
+		{"same user with stronger role", alice, []*drive.Permission{aliceWriter}, true},
+		{"same user with weaker role", aliceWriter, []*drive.Permission{alice}, false},
+		{"another user", alice, []*drive.Permission{bob}, false},
+		{"group with the same address", alice, []*drive.Permission{group}, false},
+		{"anyone covers user", external, []*drive.Permission{anyone}, true},
+		{"anyone covers domain", domain, []*drive.Permission{anyone}, true},
+		{"anyone covers anyone", anyone, []*drive.Permission{anyone}, true},
+		{"anyone doesn't cover discoverable", discoverable, []*drive.Permission{anyone}, false},
+		{"discoverable covers discoverable", discoverable, []*drive.Permission{discoverable}, true},
+		{"anyone with weaker role", domainWriter, []*drive.Permission{anyone}, false},
+		{"domain covers its user", alice, []*drive.Permission{domain}, true},
+		{"domain covers its group", group, []*drive.Permission{domain}, true},
+		{"domain doesn't cover external user", external, []*drive.Permission{domain}, false},
+		{"domain covers the same domain", domain, []*drive.Permission{domainWriter}, true},
+		{"domain with weaker role", domainWriter, []*drive.Permission{domain}, false},
+		{"domain doesn't cover anyone", anyone, []*drive.Permission{domainWriter}, false},
+		{"user doesn't cover domain", domain, []*drive.Permission{aliceWriter}, false},
+		{"any of folder permissions", alice, []*drive.Permission{bob, external, domain}, true},
+	}
+	for _, c := range cases {
+		if got := coveredBy(c.p, c.folder); got != c.want {
+			t.Errorf("%v: coveredBy = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package actions
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ken5scal/gsuite_toolkit/services"
+	"github.com/ken5scal/gsuite_toolkit/utilities"
+	"google.golang.org/api/drive/v3"
+	"os"
+	"strings"
+	"time"
+)
+
+// Kinds of remediation
+const (
+	// RemediationDomainOnly turns sharing to anyone (with the link) into sharing within the domain
+	RemediationDomainOnly = "domain-only"
+	// RemediationRemove revokes the permission
+	RemediationRemove = "remove"
+	// RemediationDowngrade changes role of the permission to reader
+	RemediationDowngrade = "downgrade"
+)
+
+// Statuses of RemediationRecord. Each remediation appends a pending record before changing anything,
+// then a record of its outcome. Undo appends an undone record, so that the remediation is not restored twice.
+// Records without status, written by older versions, are applied ones.
+const (
+	RecordPending = "pending"
+	RecordApplied = "applied"
+	RecordFailed  = "failed"
+	RecordUndone  = "undone"
+)
+
+// RemediationRecord is a line of undo log, holding the original permission before remediation.
+// Records of the same remediation share FileId, Original.Id and Time, and the last of them tells its status.
+type RemediationRecord struct {
+	FileId              string            `json:"file_id"`
+	Owner               string            `json:"owner"`
+	Remediation         string            `json:"remediation"`
+	Original            *drive.Permission `json:"original"`
+	Domain              string            `json:"domain,omitempty"`
+	DomainShared        bool              `json:"domain_shared,omitempty"`
+	CreatedPermissionId string            `json:"created_permission_id,omitempty"`
+	Time                string            `json:"time"`
+	Status              string            `json:"status,omitempty"`
+}
+
+func (r *RemediationRecord) key() string {
+	return r.FileId + "/" + r.Original.Id + "/" + r.Time
+}
+
+// driveServices returns DriveService acting as the owner of a file.
+// Without impersonate, every file is handled by the authorized user.
+type driveServices struct {
+	impersonate func(email string) (*services.DriveService, error)
+	fallback    *services.DriveService
+	cache       map[string]*services.DriveService
+}
+
+func (d *driveServices) get(owner string) (*services.DriveService, error) {
+	if d.impersonate == nil {
+		return d.fallback, nil
+	}
+	if s, ok := d.cache[owner]; ok {
+		return s, nil
+	}
+	s, err := d.impersonate(owner)
+	if err != nil {
+		return nil, err
+	}
+	d.cache[owner] = s
+	return s, nil
+}
+
+// RemediateExposures revokes or downgrades permissions of exposures matching filter.
+// Each change is appended to undoLog before it is made, and its outcome after, so that UndoRemediation can restore
+// the exact original permissions even if remediation is interrupted. With dryRun, it only prints what it would do.
+func (action DriveAction) RemediateExposures(exposures []*Exposure, filter, remediation, domain, undoLog string,
+	impersonate func(email string) (*services.DriveService, error), dryRun bool) error {
+	switch remediation {
+	case RemediationDomainOnly, RemediationRemove, RemediationDowngrade:
+	default:
+		return errors.New(fmt.Sprintf("Unknown remediation: %v. Choose from %v, %v or %v",
+			remediation, RemediationDomainOnly, RemediationRemove, RemediationDowngrade))
+	}
+
+	var rule *utilities.Rule
+	if filter != "" {
+		var err error
+		if rule, err = utilities.ParseFilter(filter); err != nil {
+			return err
+		}
+	}
+
+	var log *json.Encoder
+	if !dryRun {
+		f, err := os.OpenFile(undoLog, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		log = json.NewEncoder(f)
+	}
+
+	srvs := &driveServices{impersonate, action.DriveService, make(map[string]*services.DriveService)}
+	result := services.NewBulkResult("remediate")
+	for _, e := range exposures {
+		if rule != nil && !rule.Match(e.attributes()) {
+			continue
+		}
+		item := e.FileId + " " + e.PermissionId + " (" + e.Path + ")"
+
+		srv, err := srvs.get(e.Owner)
+		if err != nil {
+			result.Add(item, err)
+			continue
+		}
+		original, err := srv.GetPermission(e.FileId, e.PermissionId)
+		if services.IsNotFound(err) {
+			fmt.Println("already revoked: " + item)
+			continue
+		} else if err != nil {
+			result.Add(item, err)
+			continue
+		}
+
+		if !isRemediable(original, remediation) {
+			fmt.Println("skipped: " + item + " (" + original.Type + " " + original.Role + ")")
+			continue
+		}
+		fmt.Printf("%v: %v (%v %v %v)\n", remediation, item, original.Type, original.Role, e.Grantee)
+		if dryRun {
+			continue
+		}
+
+		record := &RemediationRecord{
+			FileId:      e.FileId,
+			Owner:       e.Owner,
+			Remediation: remediation,
+			Original:    original,
+			Time:        time.Now().Format(time.RFC3339),
+			Status:      RecordPending,
+		}
+		if remediation == RemediationDomainOnly {
+			// A permission for the domain which already exists must be left on undo
+			shared, err := findDomainPermission(srv, e.FileId, domain)
+			if err != nil {
+				result.Add(item, err)
+				continue
+			}
+			record.Domain, record.DomainShared = domain, shared != nil
+		}
+		if err = log.Encode(record); err != nil {
+			return err
+		}
+		err = remediate(srv, record)
+		if record.Status = RecordApplied; err != nil {
+			record.Status = RecordFailed
+		}
+		if e := log.Encode(record); e != nil {
+			return e
+		}
+		result.Add(item, err)
+	}
+	return result.Err()
+}
+
+func isRemediable(p *drive.Permission, remediation string) bool {
+	switch remediation {
+	case RemediationDomainOnly:
+		return p.Type == "anyone"
+	case RemediationDowngrade:
+		return p.Role != "reader" && p.Role != "owner"
+	case RemediationRemove:
+		return p.Role != "owner"
+	}
+	return false
+}
+
+// findDomainPermission returns the permission sharing a file with domain, or nil if there is none
+func findDomainPermission(srv *services.DriveService, fileId, domain string) (*drive.Permission, error) {
+	permissions, err := srv.GetPermissions(fileId)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range permissions {
+		if p.Type == "domain" && strings.EqualFold(p.Domain, domain) {
+			return p, nil
+		}
+	}
+	return nil, nil
+}
+
+func remediate(srv *services.DriveService, record *RemediationRecord) error {
+	original := record.Original
+	switch record.Remediation {
+	case RemediationDomainOnly:
+		if !record.DomainShared {
+			created, err := srv.CreatePermission(record.FileId, &drive.Permission{
+				Type:               "domain",
+				Domain:             record.Domain,
+				Role:               original.Role,
+				AllowFileDiscovery: original.AllowFileDiscovery,
+			})
+			if err != nil {
+				return err
+			}
+			record.CreatedPermissionId = created.Id
+		}
+		return srv.DeletePermission(record.FileId, original.Id)
+	case RemediationRemove:
+		return srv.DeletePermission(record.FileId, original.Id)
+	case RemediationDowngrade:
+		_, err := srv.UpdatePermissionRole(record.FileId, original.Id, "reader")
+		return err
+	}
+	return nil
+}
+
+// UndoRemediation restores permissions recorded in undoLog, newest first.
+// Each restored remediation is marked as undone in undoLog, so that running it again doesn't restore it twice.
+func (action DriveAction) UndoRemediation(undoLog string, impersonate func(email string) (*services.DriveService, error), dryRun bool) error {
+	f, err := os.OpenFile(undoLog, os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Only the last record of each remediation matters, in order of their first records
+	var records []*RemediationRecord
+	index := make(map[string]int)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for scanner.Scan() {
+		record := &RemediationRecord{}
+		if err = json.Unmarshal(scanner.Bytes(), record); err != nil {
+			return err
+		}
+		if i, ok := index[record.key()]; ok {
+			records[i] = record
+		} else {
+			index[record.key()] = len(records)
+			records = append(records, record)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+	log := json.NewEncoder(f)
+
+	srvs := &driveServices{impersonate, action.DriveService, make(map[string]*services.DriveService)}
+	result := services.NewBulkResult("undo remediation of")
+	for i := len(records) - 1; i >= 0; i-- {
+		r := records[i]
+		if r.Status == RecordUndone || r.Status == RecordFailed && r.CreatedPermissionId == "" {
+			// Already restored, or nothing has changed
+			continue
+		}
+		item := r.FileId + " " + r.Original.Id
+		fmt.Printf("restore %v: %v (%v %v)\n", r.Remediation, item, r.Original.Type, r.Original.Role)
+		if dryRun {
+			continue
+		}
+		srv, err := srvs.get(r.Owner)
+		if err == nil {
+			err = undo(srv, r)
+		}
+		if err == nil {
+			r.Status = RecordUndone
+			if e := log.Encode(r); e != nil {
+				return e
+			}
+		}
+		result.Add(item, err)
+	}
+	return result.Err()
+}
+
+func undo(srv *services.DriveService, record *RemediationRecord) error {
+	original := record.Original
+	if record.Remediation == RemediationDowngrade {
+		_, err := srv.UpdatePermissionRole(record.FileId, original.Id, original.Role)
+		return err
+	}
+
+	if record.Status == RecordPending && record.Remediation == RemediationDomainOnly && !record.DomainShared {
+		// Interrupted before its outcome was recorded, so the permission it may have created is looked up
+		created, err := findDomainPermission(srv, record.FileId, record.Domain)
+		if err != nil {
+			return err
+		} else if created != nil {
+			record.CreatedPermissionId = created.Id
+		}
+	}
+	if record.CreatedPermissionId != "" {
+		if err := srv.DeletePermission(record.FileId, record.CreatedPermissionId); err != nil && !services.IsNotFound(err) {
+			return err
+		}
+	}
+	if _, err := srv.GetPermission(record.FileId, original.Id); err == nil {
+		// Remediation did not complete, so the original permission still exists
+		return nil
+	} else if !services.IsNotFound(err) {
+		return err
+	}
+	_, err := srv.CreatePermission(record.FileId, &drive.Permission{
+		Type:               original.Type,
+		Role:               original.Role,
+		EmailAddress:       original.EmailAddress,
+		Domain:             original.Domain,
+		AllowFileDiscovery: original.AllowFileDiscovery,
+		ExpirationTime:     original.ExpirationTime,
+	})
+	return err
+}
@@ -0,0 +1,213 @@
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ken5scal/gsuite_toolkit/models"
+	"github.com/ken5scal/gsuite_toolkit/services"
+	"github.com/ken5scal/gsuite_toolkit/utilities"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// Risk of sensitive findings
+const (
+	RiskNone   = "none"
+	RiskLow    = "low"
+	RiskMedium = "medium"
+	RiskHigh   = "high"
+)
+
+var riskLevels = map[string]int{RiskNone: 0, RiskLow: 1, RiskMedium: 2, RiskHigh: 3}
+
+// DefaultSensitivePatterns are used when no sensitive_patterns are configured
+var DefaultSensitivePatterns = []models.SensitivePattern{
+	{Name: "password", Field: "name", Pattern: `(?i)passw(or)?d|パスワード`, Severity: 3},
+	{Name: "credential", Field: "name", Pattern: `(?i)secret|credential|api[_ -]?key|秘密鍵`, Severity: 3},
+	{Name: "my_number", Field: "name", Pattern: `マイナンバー|個人番号`, Severity: 3},
+	{Name: "personal_data", Field: "name", Pattern: `個人情報|(?i)personal[_ -]?data`, Severity: 2},
+	{Name: "credit_card", Field: "description", Pattern: `\b(?:\d[ -]?){13,16}\b`, Severity: 3},
+}
+
+// SensitiveRule is a compiled SensitivePattern
+type SensitiveRule struct {
+	Name     string
+	Field    string
+	Pattern  *regexp.Regexp
+	Severity int
+}
+
+// SensitiveFinding is a file matching a sensitive rule, rated by its exposure.
+// Score is severity of the rule multiplied by weight of the widest sharing of the file.
+type SensitiveFinding struct {
+	Id      string `json:"id"`
+	Path    string `json:"path"`
+	Owner   string `json:"owner"`
+	Rule    string `json:"rule"`
+	Field   string `json:"field"`
+	Match   string `json:"match"`
+	Sharing string `json:"sharing"`
+	Score   int    `json:"score"`
+	Risk    string `json:"risk"`
+}
+
+// CompileSensitiveRules validates patterns, or returns DefaultSensitivePatterns if patterns is empty
+func CompileSensitiveRules(patterns []models.SensitivePattern) ([]*SensitiveRule, error) {
+	if len(patterns) == 0 {
+		patterns = DefaultSensitivePatterns
+	}
+
+	var rules []*SensitiveRule
+	for _, p := range patterns {
+		if p.Field != "name" && p.Field != "description" {
+			return nil, errors.New(fmt.Sprintf("Field of %v must be name or description: %v", p.Name, p.Field))
+		} else if p.Severity < 1 || p.Severity > 3 {
+			return nil, errors.New(fmt.Sprintf("Severity of %v must be from 1 to 3: %d", p.Name, p.Severity))
+		}
+		r, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid pattern of %v: %v", p.Name, err))
+		}
+		rules = append(rules, &SensitiveRule{p.Name, p.Field, r, p.Severity})
+	}
+	return rules, nil
+}
+
+// ScanSensitive matches rules against names and descriptions of files, and lists findings at or above minRisk
+// ordered by score. Files are read from inventory written by `drive inventory` if input is given,
+// otherwise My Drive and Team Drives are walked as Inventory does.
+func (action DriveAction) ScanSensitive(input string, domains []string, parallel int, rules []*SensitiveRule,
+	minRisk, format string) error {
+	if err := utilities.ValidateOutputFormat(format); err != nil {
+		return err
+	}
+	min, ok := riskLevels[minRisk]
+	if !ok {
+		return errors.New(fmt.Sprintf("Unknown risk: %v. Choose from %v, %v, %v or %v", minRisk, RiskNone, RiskLow, RiskMedium, RiskHigh))
+	}
+
+	var findings []*SensitiveFinding
+	scan := func(item *InventoryItem) error {
+		for _, f := range MatchSensitive(item, rules) {
+			if riskLevels[f.Risk] >= min {
+				findings = append(findings, f)
+			}
+		}
+		return nil
+	}
+
+	var err error
+	if input != "" {
+		var items []*InventoryItem
+		if items, err = LoadInventory(input); err != nil {
+			return err
+		}
+		for _, item := range items {
+			scan(item)
+		}
+	} else if err = action.walkInventory("root", domains, parallel, true, scan); err != nil {
+		if _, partial := err.(*services.BulkResult); !partial {
+			return err
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Score > findings[j].Score })
+	if e := writeSensitiveFindings(format, findings); e != nil {
+		return e
+	}
+	return err
+}
+
+// MatchSensitive lists findings of an inventory item
+func MatchSensitive(item *InventoryItem, rules []*SensitiveRule) []*SensitiveFinding {
+	var findings []*SensitiveFinding
+	sharing, weight := sharingWeight(item.Sharing)
+	for _, r := range rules {
+		text := item.Name
+		if r.Field == "description" {
+			text = item.Description
+		}
+		match := r.Pattern.FindString(text)
+		if match == "" {
+			continue
+		}
+		score := r.Severity * weight
+		findings = append(findings, &SensitiveFinding{
+			Id:      item.Id,
+			Path:    item.Path,
+			Owner:   item.Owner,
+			Rule:    r.Name,
+			Field:   r.Field,
+			Match:   maskDigits(match),
+			Sharing: sharing,
+			Score:   score,
+			Risk:    rateRisk(score),
+		})
+	}
+	return findings
+}
+
+// sharingWeight weights the widest sharing of a file. Files nobody else can access are weighted 0.
+func sharingWeight(s SharingSummary) (string, int) {
+	switch s.Exposure {
+	case ExposurePublic:
+		return s.Exposure, 4
+	case ExposureAnyoneWithLink, ExposureExternalDomain:
+		return s.Exposure, 3
+	case ExposureExternalUser:
+		return s.Exposure, 2
+	}
+	if s.Domains > 0 || s.Groups > 0 || s.Users > 0 {
+		return "internal", 1
+	}
+	return "private", 0
+}
+
+// rateRisk rates score. High risk is a severe match shared outside, such as a password file shared to anyone with the link.
+func rateRisk(score int) string {
+	switch {
+	case score >= 8:
+		return RiskHigh
+	case score >= 4:
+		return RiskMedium
+	case score >= 1:
+		return RiskLow
+	}
+	return RiskNone
+}
+
+// maskDigits hides digits of a long number such as credit card except the last 4, so that output doesn't leak it
+func maskDigits(s string) string {
+	digits := 0
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			digits++
+		}
+	}
+	if digits < 8 {
+		return s
+	}
+
+	masked := []rune(s)
+	for i := range masked {
+		if masked[i] >= '0' && masked[i] <= '9' && digits > 4 {
+			masked[i] = '*'
+			digits--
+		}
+	}
+	return string(masked)
+}
+
+func writeSensitiveFindings(format string, findings []*SensitiveFinding) error {
+	if format == utilities.JSON {
+		return utilities.WriteJSON(os.Stdout, findings)
+	}
+
+	var rows [][]string
+	for _, f := range findings {
+		rows = append(rows, []string{f.Id, f.Path, f.Owner, f.Rule, f.Field, f.Match, f.Sharing, strconv.Itoa(f.Score), f.Risk})
+	}
+	return utilities.WriteCSV(os.Stdout, []string{"id", "path", "owner", "rule", "field", "match", "sharing", "score", "risk"}, rows)
+}
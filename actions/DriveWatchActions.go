@@ -0,0 +1,119 @@
+package actions
+
+import (
+	"github.com/ken5scal/gsuite_toolkit/models"
+	"github.com/ken5scal/gsuite_toolkit/utilities"
+	"google.golang.org/api/drive/v3"
+	"log"
+	"time"
+)
+
+// Watch polls Changes API every interval and alerts exposures newly added to changed files.
+// Page token and alerted permissions are persisted in statePath, so that watch resumes where it stopped.
+// At the first run, it only records the current page token, so that existing files are not alerted.
+// Only exposures matching rule are alerted, or every exposure if rule is nil. With once, it polls only once.
+func (action DriveAction) Watch(statePath string, domains []string, rule *utilities.Rule, notifier utilities.Notifier,
+	interval time.Duration, once bool) error {
+	state, err := models.LoadWatchState(statePath)
+	if err != nil {
+		return err
+	}
+	if state.PageToken == "" {
+		if state.PageToken, err = action.DriveService.GetStartPageToken(); err != nil {
+			return err
+		}
+		if err = models.SaveWatchState(statePath, state); err != nil {
+			return err
+		}
+		log.Printf("Started watching changes from page token %v", state.PageToken)
+	}
+
+	for {
+		if err = action.pollChanges(statePath, state, domains, rule, notifier); err != nil {
+			if once {
+				return err
+			}
+			// Changes since the last saved page token are retried at the next poll
+			log.Printf("Failed polling changes: %v", err)
+		}
+		if once {
+			return nil
+		}
+		time.Sleep(interval)
+	}
+}
+
+func (action DriveAction) pollChanges(statePath string, state *models.WatchState, domains []string, rule *utilities.Rule,
+	notifier utilities.Notifier) error {
+	changes, next, err := action.DriveService.GetChanges(state.PageToken)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range changes {
+		if c.Removed || c.File == nil || c.File.Trashed {
+			delete(state.Alerted, c.FileId)
+			continue
+		}
+		// Changes API doesn't populate permissions of files in Team Drives
+		if err = action.fillTeamDrivePermissions([]*drive.File{c.File}); err != nil {
+			return err
+		}
+		exposures, err := collectExposures(action.DriveService, []*drive.File{c.File}, domains)
+		if err != nil {
+			return err
+		}
+		forgetRevoked(state, c.FileId, exposures)
+		for _, e := range exposures {
+			if state.IsAlerted(e.FileId, e.PermissionId) {
+				continue
+			}
+			if rule == nil || rule.Match(e.attributes()) {
+				if err = notifier.Notify(exposureAlert(e)); err != nil {
+					return err
+				}
+			}
+			state.SetAlerted(e.FileId, e.PermissionId)
+			if err = models.SaveWatchState(statePath, state); err != nil {
+				return err
+			}
+		}
+	}
+
+	state.PageToken = next
+	return models.SaveWatchState(statePath, state)
+}
+
+// forgetRevoked forgets alerted permissions which no longer expose a file, so that they are alerted again if re-shared
+func forgetRevoked(state *models.WatchState, fileId string, exposures []*Exposure) {
+	var alerted []string
+	for _, e := range exposures {
+		if state.IsAlerted(fileId, e.PermissionId) {
+			alerted = append(alerted, e.PermissionId)
+		}
+	}
+	if len(alerted) == 0 {
+		delete(state.Alerted, fileId)
+	} else {
+		state.Alerted[fileId] = alerted
+	}
+}
+
+func exposureAlert(e *Exposure) *utilities.Alert {
+	grantee := e.Grantee
+	if grantee == "" {
+		grantee = "anyone"
+	}
+	return &utilities.Alert{
+		Title: "File shared: " + e.Exposure,
+		Text:  e.Path + " is shared with " + grantee + " as " + e.Role,
+		Fields: map[string]string{
+			"file_id":  e.FileId,
+			"owner":    e.Owner,
+			"exposure": e.Exposure,
+			"role":     e.Role,
+			"grantee":  e.Grantee,
+			"modified": e.ModifiedTime,
+		},
+	}
+}
@@ -1,14 +1,59 @@
 package actions
 
 import (
+	"github.com/ken5scal/gsuite_toolkit/models"
 	"github.com/ken5scal/gsuite_toolkit/services"
+	"github.com/ken5scal/gsuite_toolkit/utilities"
+	"google.golang.org/api/admin/directory/v1"
 	"fmt"
 	"errors"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
 )
 
+const (
+	MemberKindExternal = "external"
+	MemberKindGmail    = "gmail"
+	MemberKindDomain   = "domain"
+
+	GroupUsageMailingList = "mailing_list"
+	GroupUsageAccess      = "access"
+
+	noOwner = "(no owner)"
+
+	IssueNoOwner       = "no_owner"
+	IssueInactiveOwner = "inactive_owner"
+	IssueEmpty         = "empty"
+	IssueNoActivity    = "no_activity"
+	IssueSimilarName   = "similar_name"
+)
+
+// ExternalMember is a member of a group who does not belong to own domains
+type ExternalMember struct {
+	Owner  string `json:"owner"`
+	Group  string `json:"group"`
+	Usage  string `json:"usage"`
+	Member string `json:"member"`
+	Kind   string `json:"kind"`
+}
+
+// GroupFinding is an unhealthy state of a group detected by hygiene check
+type GroupFinding struct {
+	Group          string `json:"group"`
+	Issue          string `json:"issue"`
+	Detail         string `json:"detail"`
+	SuggestedOwner string `json:"suggested_owner"`
+}
+
 // GroupAction
 type GroupAction struct {
 	*services.GroupService
+	user     *services.UserService
+	activity *services.AuditActivitiesService
+	mail     *services.MailService
 }
 
 // InitGroupAction initializes Group
@@ -17,11 +62,20 @@ func InitGroupAction() *GroupAction {
 }
 
 // SetService sets service in Action.
+// UserService, AuditActivitiesService and MailService are optional and only required by some actions.
 func (action *GroupAction) SetService(s services.Service) error {
-	if _, ok := s.(*services.GroupService); !ok {
+	switch s.(type) {
+	case *services.GroupService:
+		action.GroupService = s.(*services.GroupService)
+	case *services.UserService:
+		action.user = s.(*services.UserService)
+	case *services.AuditActivitiesService:
+		action.activity = s.(*services.AuditActivitiesService)
+	case *services.MailService:
+		action.mail = s.(*services.MailService)
+	default:
 		return errors.New(fmt.Sprintf("Invalid type: %T", s))
 	}
-	action.GroupService = s.(*services.GroupService)
 	return nil
 }
 
@@ -33,6 +87,9 @@ func (action GroupAction) GetGroup(email string) error {
 		fmt.Println(g.Email)
 		fmt.Println(g.Description)
 		fmt.Println(g.AdminCreated)
+		if len(g.Aliases) > 0 {
+			fmt.Println("Aliases: " + strings.Join(g.Aliases, ", "))
+		}
 		return nil
 	}
 }
@@ -43,12 +100,63 @@ func (action GroupAction) RetrieveAllGroups(domain string) error {
 		return err
 	} else {
 		for _, group := range g {
-			fmt.Println(group.Name + " - " + group.Email)
+			if len(group.Aliases) > 0 {
+				fmt.Println(group.Name + " - " + group.Email + " (" + strings.Join(group.Aliases, ", ") + ")")
+			} else {
+				fmt.Println(group.Name + " - " + group.Email)
+			}
 		}
 	}
 	return nil
 }
 
+// ListAliases prints aliases of a group
+func (action GroupAction) ListAliases(groupEmail string) error {
+	aliases, err := action.GroupService.GetAliases(groupEmail)
+	if err != nil {
+		return err
+	}
+	for _, alias := range aliases {
+		fmt.Println(alias)
+	}
+	return nil
+}
+
+// AddAlias adds an alias to a group after checking no user or group uses the address.
+func (action GroupAction) AddAlias(groupEmail, alias string) error {
+	if action.user == nil {
+		return errors.New("UserService must be set")
+	}
+
+	// Both lookups resolve aliases as well as primary addresses
+	if u, err := action.user.GetUser(alias); err == nil {
+		return errors.New(fmt.Sprintf("%v is already used by user %v", alias, u.PrimaryEmail))
+	} else if !services.IsNotFound(err) {
+		return err
+	}
+	if g, err := action.GroupService.GetGroup(alias); err == nil {
+		return errors.New(fmt.Sprintf("%v is already used by group %v", alias, g.Email))
+	} else if !services.IsNotFound(err) {
+		return err
+	}
+
+	if _, err := action.GroupService.AddAlias(groupEmail, alias); err != nil {
+		return err
+	}
+	fmt.Println("Added " + alias + " to " + groupEmail)
+	return nil
+}
+
+// RemoveAlias removes an alias from a group
+func (action GroupAction) RemoveAlias(groupEmail, alias string) error {
+	if err := action.GroupService.RemoveAlias(groupEmail, alias); err != nil {
+		return err
+	}
+	fmt.Println("Removed " + alias + " from " + groupEmail)
+	return nil
+}
+
+
 // SearchGroupsByEmail searches groups where email account belongs.
 func (action GroupAction) SearchGroupsByEmail(domain, email string) error {
 	if g, err := action.GroupService.RetrieveAllGroups(domain, email); err != nil {
@@ -59,4 +167,447 @@ func (action GroupAction) SearchGroupsByEmail(domain, email string) error {
 		}
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+// ReportExternalMembers reports groups containing members outside of domains, grouped by group owner.
+// domains should contain primary domain and its aliases. The first one is used to list groups.
+func (action GroupAction) ReportExternalMembers(domains []string, format string) error {
+	if err := utilities.ValidateOutputFormat(format); err != nil {
+		return err
+	}
+
+	groups, err := action.GroupService.RetrieveAllGroups(domains[0], "")
+	if err != nil {
+		return err
+	}
+
+	report := make(map[string][]*ExternalMember)
+	for _, group := range groups {
+		members, err := action.GroupService.GetMembers(group.Email)
+		if err != nil {
+			return err
+		}
+
+		var owners []string
+		var externals []*ExternalMember
+		for _, m := range members {
+			if m.Role == "OWNER" {
+				owners = append(owners, m.Email)
+			}
+			if kind := classifyMember(m, domains); kind != "" {
+				externals = append(externals, &ExternalMember{Group: group.Email, Member: m.Email, Kind: kind})
+			}
+		}
+		if len(externals) == 0 {
+			continue
+		}
+
+		usage, err := action.getGroupUsage(group.Email)
+		if err != nil {
+			return err
+		}
+		if len(owners) == 0 {
+			owners = []string{noOwner}
+		}
+		for _, owner := range owners {
+			for _, e := range externals {
+				report[owner] = append(report[owner], &ExternalMember{owner, e.Group, usage, e.Member, e.Kind})
+			}
+		}
+	}
+
+	if format == utilities.JSON {
+		return utilities.WriteJSON(os.Stdout, report)
+	}
+
+	owners := make([]string, 0, len(report))
+	for owner := range report {
+		owners = append(owners, owner)
+	}
+	sort.Strings(owners)
+
+	var rows [][]string
+	for _, owner := range owners {
+		for _, e := range report[owner] {
+			rows = append(rows, []string{e.Owner, e.Group, e.Usage, e.Member, e.Kind})
+		}
+	}
+	return utilities.WriteCSV(os.Stdout, []string{"owner", "group", "usage", "member", "kind"}, rows)
+}
+
+// getGroupUsage judges whether a group is used as mailing list or for granting access such as Drive.
+// Groups nobody can post to are regarded as access groups.
+func (action GroupAction) getGroupUsage(groupEmail string) (string, error) {
+	settings, err := action.GroupService.GetSettings(groupEmail)
+	if err != nil {
+		return "", err
+	}
+	if settings.WhoCanPostMessage == "NONE_CAN_POST" {
+		return GroupUsageAccess, nil
+	}
+	return GroupUsageMailingList, nil
+}
+
+// classifyMember returns kind of member if it does not belong to domains, otherwise empty string.
+func classifyMember(m *admin.Member, domains []string) string {
+	if m.Type == "CUSTOMER" {
+		// Every user in the organization is a member
+		return MemberKindDomain
+	}
+
+	email := strings.ToLower(m.Email)
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+
+	switch domain := email[at+1:]; domain {
+	case "gmail.com", "googlemail.com":
+		return MemberKindGmail
+	default:
+		for _, d := range domains {
+			if strings.EqualFold(domain, d) {
+				return ""
+			}
+		}
+	}
+	return MemberKindExternal
+}
+
+// CheckGroupHygiene reports groups without owners, groups whose owners are all suspended or deleted,
+// empty groups, groups without activity in inactiveDays and groups with similar names.
+// threshold is minimum similarity (0 to 1) of group names regarded as near-duplicates.
+func (action GroupAction) CheckGroupHygiene(domain string, inactiveDays int, threshold float64, format string) error {
+	if err := utilities.ValidateOutputFormat(format); err != nil {
+		return err
+	} else if action.user == nil || action.activity == nil {
+		return errors.New("UserService and AuditActivitiesService must be set")
+	} else if inactiveDays < 1 || inactiveDays > 180 {
+		// Reports API keeps activities only for 180 days
+		return errors.New("days must be between 1 and 180")
+	}
+
+	groups, err := action.GroupService.RetrieveAllGroups(domain, "")
+	if err != nil {
+		return err
+	}
+	employees, err := action.user.GetEmployees(domain)
+	if err != nil {
+		return err
+	}
+	users := make(map[string]*admin.User, len(employees))
+	for _, u := range employees {
+		users[strings.ToLower(u.PrimaryEmail)] = u
+	}
+
+	activities, err := action.activity.GetGroupActivities(time.Now().AddDate(0, 0, -inactiveDays))
+	if err != nil {
+		return err
+	}
+	activeGroups := make(map[string]bool)
+	for _, activity := range activities {
+		for _, event := range activity.Events {
+			for _, p := range event.Parameters {
+				if p.Name == "group_email" {
+					activeGroups[strings.ToLower(p.Value)] = true
+				}
+			}
+		}
+	}
+
+	var findings []*GroupFinding
+	suggestedOwners := make(map[string]string, len(groups))
+	for _, group := range groups {
+		members, err := action.GroupService.GetMembers(group.Email)
+		if err != nil {
+			return err
+		}
+
+		suggestedOwner := suggestOwner(members, users)
+		suggestedOwners[group.Email] = suggestedOwner
+		addFinding := func(issue, detail string) {
+			findings = append(findings, &GroupFinding{group.Email, issue, detail, suggestedOwner})
+		}
+
+		var owners, activeOwners []string
+		for _, m := range members {
+			if m.Role != "OWNER" {
+				continue
+			}
+			owners = append(owners, m.Email)
+			if isActiveMember(m, users, domain) {
+				activeOwners = append(activeOwners, m.Email)
+			}
+		}
+
+		if len(members) == 0 {
+			addFinding(IssueEmpty, "")
+		}
+		if len(owners) == 0 {
+			addFinding(IssueNoOwner, "")
+		} else if len(activeOwners) == 0 {
+			addFinding(IssueInactiveOwner, "suspended or deleted: "+strings.Join(owners, " "))
+		}
+		if !activeGroups[strings.ToLower(group.Email)] {
+			addFinding(IssueNoActivity, fmt.Sprintf("no activity in %d days", inactiveDays))
+		}
+	}
+
+	for i := 0; i < len(groups); i++ {
+		for j := i + 1; j < len(groups); j++ {
+			if utilities.Similarity(groups[i].Name, groups[j].Name) >= threshold {
+				findings = append(findings, &GroupFinding{groups[i].Email, IssueSimilarName, "similar to " + groups[j].Email,
+					suggestedOwners[groups[i].Email]})
+			}
+		}
+	}
+
+	if format == utilities.JSON {
+		return utilities.WriteJSON(os.Stdout, findings)
+	}
+	var rows [][]string
+	for _, f := range findings {
+		rows = append(rows, []string{f.Group, f.Issue, f.Detail, f.SuggestedOwner})
+	}
+	return utilities.WriteCSV(os.Stdout, []string{"group", "issue", "detail", "suggested_owner"}, rows)
+}
+
+// isActiveMember checks whether a member is neither suspended nor deleted.
+// Members outside of domain are regarded as active because their status is unknown.
+func isActiveMember(m *admin.Member, users map[string]*admin.User, domain string) bool {
+	email := strings.ToLower(m.Email)
+	if u, ok := users[email]; ok {
+		return !u.Suspended
+	}
+	return !strings.HasSuffix(email, "@"+strings.ToLower(domain))
+}
+
+// suggestOwner returns the most senior active manager of a group, judged by account creation time.
+func suggestOwner(members []*admin.Member, users map[string]*admin.User) string {
+	var suggested string
+	var oldest time.Time
+	for _, m := range members {
+		if m.Role != "MANAGER" {
+			continue
+		}
+		u, ok := users[strings.ToLower(m.Email)]
+		if !ok || u.Suspended {
+			continue
+		}
+		created, err := time.Parse(time.RFC3339, u.CreationTime)
+		if err != nil {
+			continue
+		}
+		if suggested == "" || created.Before(oldest) {
+			suggested, oldest = u.PrimaryEmail, created
+		}
+	}
+	return suggested
+}
+
+// SyncDynamicGroups adds users matching rule of each dynamic group and removes members who no longer match.
+// Only members with MEMBER role are removed. Owners, managers and nested groups are left as they are.
+// Removals are capped by MaxRemovals of each group and logged. With dryRun, it only prints the plan.
+// Failures do not stop the sync and are returned together as *services.BulkResult.
+// Outcome of each addition and removal is written to stderr as CSV.
+func (action GroupAction) SyncDynamicGroups(domain string, dynamicGroups []models.DynamicGroup, dryRun bool) error {
+	if action.user == nil {
+		return errors.New("UserService must be set")
+	}
+
+	rules := make([]*utilities.Rule, len(dynamicGroups))
+	for i, g := range dynamicGroups {
+		r, err := utilities.ParseRule(g.Rule)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Invalid rule of %v: %v", g.Email, err))
+		}
+		rules[i] = r
+	}
+
+	users, err := action.user.GetEmployees(domain)
+	if err != nil {
+		return err
+	}
+
+	result := services.NewBulkResult("sync group member")
+	for i, g := range dynamicGroups {
+		desired := make(map[string]bool)
+		for _, u := range users {
+			if !u.Suspended && rules[i].Match(services.GetUserAttributes(u)) {
+				desired[strings.ToLower(u.PrimaryEmail)] = true
+			}
+		}
+
+		members, err := action.GroupService.GetMembers(g.Email)
+		if err != nil {
+			result.Add(g.Email, err)
+			continue
+		}
+		current := make(map[string]bool)
+		var removals []string
+		for _, m := range members {
+			email := strings.ToLower(m.Email)
+			current[email] = true
+			if m.Role == "MEMBER" && m.Type == "USER" && !desired[email] {
+				removals = append(removals, email)
+			}
+		}
+
+		var additions []string
+		for email := range desired {
+			if !current[email] {
+				additions = append(additions, email)
+			}
+		}
+		sort.Strings(additions)
+		sort.Strings(removals)
+
+		fmt.Printf("%v (%v): %d to add, %d to remove\n", g.Email, rules[i], len(additions), len(removals))
+		if len(removals) > g.MaxRemovals {
+			log.Printf("%v: %d removals exceed max_removals %d. Skipped: %v",
+				g.Email, len(removals), g.MaxRemovals, strings.Join(removals[g.MaxRemovals:], " "))
+			removals = removals[:g.MaxRemovals]
+		}
+
+		for _, email := range additions {
+			fmt.Println("	+ " + email)
+			if dryRun {
+				continue
+			}
+			_, err := action.GroupService.AddMember(g.Email, email, "MEMBER")
+			result.Add(g.Email+" + "+email, err)
+		}
+		for _, email := range removals {
+			fmt.Println("	- " + email)
+			if dryRun {
+				continue
+			}
+			err := action.GroupService.RemoveMember(g.Email, email)
+			result.Add(g.Email+" - "+email, err)
+			if err == nil {
+				log.Printf("%v: removed %v", g.Email, email)
+			}
+		}
+	}
+	if err = result.Write(os.Stderr, utilities.CSV); err != nil {
+		return err
+	}
+	return result.Err()
+}
+
+// AddMember adds a member to a group.
+// If expires is positive, the membership is recorded in grantsFile and removed by ExpireMembers after expiry.
+func (action GroupAction) AddMember(groupEmail, email, role string, expires time.Duration, grantsFile string) error {
+	if expires < 0 {
+		return errors.New("expires must be positive")
+	}
+
+	var grants []*models.Grant
+	var err error
+	if expires > 0 {
+		// Load state first so that a broken state file does not leave an unrecorded membership.
+		if grants, err = models.LoadGrants(grantsFile); err != nil {
+			return err
+		}
+	}
+
+	if _, err = action.GroupService.AddMember(groupEmail, email, role); err != nil {
+		return err
+	}
+	if expires == 0 {
+		fmt.Println("Added " + email + " to " + groupEmail)
+		return nil
+	}
+
+	now := time.Now()
+	grant := &models.Grant{Group: groupEmail, Member: email, Role: role, GrantedAt: now, Expires: now.Add(expires)}
+	if err = models.SaveGrants(grantsFile, append(grants, grant)); err != nil {
+		return err
+	}
+	fmt.Println("Added " + email + " to " + groupEmail + " until " + grant.Expires.Format(time.RFC3339))
+	return nil
+}
+
+// RemoveMember removes a member from a group
+func (action GroupAction) RemoveMember(groupEmail, email string) error {
+	if err := action.GroupService.RemoveMember(groupEmail, email); err != nil {
+		return err
+	}
+	fmt.Println("Removed " + email + " from " + groupEmail)
+	return nil
+}
+
+// ExpireMembers removes expired memberships recorded in grantsFile, notifies owners of each group
+// and prints what it removed. Memberships failed to be removed are kept in grantsFile for next run.
+// Outcome of each removal is written to stderr as CSV.
+func (action GroupAction) ExpireMembers(grantsFile string) error {
+	grants, err := models.LoadGrants(grantsFile)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var remaining []*models.Grant
+	removed := make(map[string][]*models.Grant)
+	result := services.NewBulkResult("remove expired member")
+	for _, g := range grants {
+		if !g.IsExpired(now) {
+			remaining = append(remaining, g)
+			continue
+		}
+		// Member might have been removed manually
+		err := action.GroupService.RemoveMember(g.Group, g.Member)
+		if services.IsNotFound(err) {
+			err = nil
+		}
+		result.Add(g.Group+" - "+g.Member, err)
+		if err != nil {
+			remaining = append(remaining, g)
+			continue
+		}
+		fmt.Println(g.Group + "	" + g.Member + "	expired at " + g.Expires.Format(time.RFC3339))
+		removed[g.Group] = append(removed[g.Group], g)
+	}
+
+	if err = models.SaveGrants(grantsFile, remaining); err != nil {
+		return err
+	}
+
+	for group, gs := range removed {
+		if err := action.notifyExpiry(group, gs); err != nil {
+			log.Printf("Failed notifying owners of %v: %v", group, err)
+		}
+	}
+
+	if err = result.Write(os.Stderr, utilities.CSV); err != nil {
+		return err
+	}
+	return result.Err()
+}
+
+// notifyExpiry sends a mail to owners of group about expired memberships
+func (action GroupAction) notifyExpiry(group string, grants []*models.Grant) error {
+	if action.mail == nil {
+		return nil
+	}
+
+	members, err := action.GroupService.GetMembers(group)
+	if err != nil {
+		return err
+	}
+	var owners []string
+	for _, m := range members {
+		if m.Role == "OWNER" {
+			owners = append(owners, m.Email)
+		}
+	}
+	if len(owners) == 0 {
+		return nil
+	}
+
+	body := "Following temporary memberships of " + group + " have expired and been removed.\n\n"
+	for _, g := range grants {
+		body += g.Member + " (granted at " + g.GrantedAt.Format(time.RFC3339) + ", expired at " + g.Expires.Format(time.RFC3339) + ")\n"
+	}
+	return action.mail.SendMail(owners, "[gsuite] Expired memberships of "+group, body)
+}
@@ -4,10 +4,12 @@ import (
 	"fmt"
 	"errors"
 	"google.golang.org/api/admin/reports/v1"
+	directory "google.golang.org/api/admin/directory/v1"
 	"github.com/ken5scal/gsuite_toolkit/services"
 	"github.com/ken5scal/gsuite_toolkit/utilities"
 	"time"
 	"github.com/asaskevich/govalidator"
+	"os"
 )
 
 type LoginAction struct {
@@ -33,11 +35,8 @@ func (action *LoginAction) SetService(s services.Service) error {
 }
 
 // TODO Check Admin Login
-func (action LoginAction) GetAllAdminUsers(domain string) error {
-	// Just for fun
-	f, _ := action.user.ConstructOuterRequest()
-	fmt.Println(f)
-
+// GetAllAdminUsers prints admins and delegated admins, or their numbers per org unit if report is given
+func (action LoginAction) GetAllAdminUsers(domain string, report *OrgUnitReport) error {
 	// TODO Make this chan
 	users, err := action.user.GetAllAdmins(domain)
 	if err != nil {
@@ -50,17 +49,24 @@ func (action LoginAction) GetAllAdminUsers(domain string) error {
 		return err
 	}
 	users = append(users, hoge...)
+	if report != nil {
+		return action.writeOrgUnitReport(domain, report, users, nil)
+	}
 	for _, user := range users {
 		fmt.Println(user.PrimaryEmail)
 	}
 	return nil
 }
 
-func (action LoginAction) GetNon2StepVerifiedUsers() error {
-	us, err := action.user.GetNon2SVEmployees("moneyforward.co.jp")
+// GetNon2StepVerifiedUsers prints users without 2SV, or their numbers per org unit if report is given
+func (action LoginAction) GetNon2StepVerifiedUsers(domain string, report *OrgUnitReport) error {
+	us, err := action.user.GetNon2SVEmployees(domain)
 	if err != nil {
 		return err
 	}
+	if report != nil {
+		return action.writeOrgUnitReport(domain, report, us, nil)
+	}
 
 	for _, u := range us {
 		fmt.Println(u.PrimaryEmail)
@@ -69,26 +75,50 @@ func (action LoginAction) GetNon2StepVerifiedUsers() error {
 	return nil
 }
 
+// writeOrgUnitReport aggregates offenders per org unit against all employees
+func (action LoginAction) writeOrgUnitReport(domain string, report *OrgUnitReport, offenders []*directory.User, scores map[string]float64) error {
+	users, err := action.user.GetEmployees(domain)
+	if err != nil {
+		return err
+	}
+	return report.Write(os.Stdout, users, offenders, scores)
+}
+
 func (action LoginAction) GetAllLoginActivities(daysAgo int) ([]*admin.Activity, error) {
-	activities, err := action.activity.GetLoginActivities(daysAgo)
+	activities, err := action.activity.GetLoginActivities(time.Now().AddDate(0, 0, -daysAgo), time.Time{})
 	if err != nil {
 		return nil, err
 	}
 	return activities, nil
 }
 
-func (action *LoginAction) GetUsersWithRareLogin(daysAgo int, name string) error {
+// GetUsersWithRareLogin prints users who have not logged in for daysAgo,
+// or their numbers per org unit ranked by days since last login if report is given
+func (action *LoginAction) GetUsersWithRareLogin(daysAgo int, name string, report *OrgUnitReport) error {
 	r, err := action.user.GetUsersWithRareLogin(daysAgo, name)
 	if err != nil {
 		return err
 	}
+	if report != nil {
+		scores := make(map[string]float64, len(r))
+		for _, user := range r {
+			if lastLogin, err := time.Parse(time.RFC3339, user.LastLoginTime); err == nil {
+				scores[user.PrimaryEmail] = time.Since(lastLogin).Hours() / 24
+			}
+		}
+		return action.writeOrgUnitReport(name, report, r, scores)
+	}
 	for _, user := range r {
 		fmt.Println(user.PrimaryEmail)
 	}
 	return nil
 }
 
-func  (action *LoginAction)  GetIllegalLoginUsersAndIp2(officeIPs []string) error {
+// GetIllegalLoginUsersAndIp2 reports logins from outside of offices and suspicious logins judged by Google within window.
+// Without window, logins of 45 days and suspicious logins since the first day of last month are examined.
+// If report is given, users are aggregated per org unit ranked by number of such logins.
+func  (action *LoginAction)  GetIllegalLoginUsersAndIp2(officeIPs []string, domain string, window *utilities.TimeWindow,
+	report *OrgUnitReport) error {
 	// Todo this is bad
 	// ToDo Make this chan
 	// Wow this really needs to be Chan
@@ -98,23 +128,30 @@ func  (action *LoginAction)  GetIllegalLoginUsersAndIp2(officeIPs []string) erro
 		}
 	}
 
-	activities, err := action.activity.GetLoginActivities(45)
-	if err != nil {
-		return err
+	loginWindow, suspiciousWindow := window, window
+	if window == nil || window.Since.IsZero() && window.Until.IsZero() {
+		loginWindow = &utilities.TimeWindow{Since: time.Now().AddDate(0, 0, -45)}
+		suspiciousWindow = defaultWindow(nil)
 	}
 
-	filteredActivities := getIllegalLoginUsersAndIHogep2(activities, officeIPs)
+	activities, err := action.activity.GetLoginActivities(loginWindow.Since, loginWindow.Until)
 	if err != nil {
 		return err
 	}
 
-	firstDayOfLastMonth := utilities.Last_Month.ModifyDate(time.Now())
-	suspiciousActivitiesJudgedByGoogle, err :=  action.activity.GetSuspiciousLogIns(firstDayOfLastMonth)
+	suspiciousActivitiesJudgedByGoogle, err :=  action.activity.GetSuspiciousLogIns(suspiciousWindow.Since, suspiciousWindow.Until)
 	if err != nil {
 		return err
 	}
 
 
+	if report != nil {
+		return action.writeSuspiciousLoginReport(domain, report, activities, suspiciousActivitiesJudgedByGoogle, officeIPs)
+	}
+
+	// It prints logins from outside of offices, which must not be mixed into the report above
+	filteredActivities := getIllegalLoginUsersAndIHogep2(activities, officeIPs)
+
 	// ToDO Check if there is duplicates.
 	suspiciousActivitiesJudgedByGoogle = append(suspiciousActivitiesJudgedByGoogle, filteredActivities...)
 	//for _, activity := range filteredActivities {
@@ -123,6 +160,38 @@ func  (action *LoginAction)  GetIllegalLoginUsersAndIp2(officeIPs []string) erro
 	return nil
 }
 
+// writeSuspiciousLoginReport scores users by number of logins of users who never logged in from office,
+// and suspicious logins judged by Google, then aggregates them per org unit.
+func (action *LoginAction) writeSuspiciousLoginReport(domain string, report *OrgUnitReport, logins, suspicious []*admin.Activity, officeIPs []string) error {
+	scores := make(map[string]float64)
+	officeLogin := make(map[string]bool)
+	for _, activity := range logins {
+		if containIP(officeIPs, activity.IpAddress) {
+			officeLogin[activity.Actor.Email] = true
+		}
+	}
+	for _, activity := range logins {
+		if !officeLogin[activity.Actor.Email] {
+			scores[activity.Actor.Email]++
+		}
+	}
+	for _, activity := range suspicious {
+		scores[activity.Actor.Email]++
+	}
+
+	users, err := action.user.GetEmployees(domain)
+	if err != nil {
+		return err
+	}
+	var offenders []*directory.User
+	for _, u := range users {
+		if scores[u.PrimaryEmail] > 0 {
+			offenders = append(offenders, u)
+		}
+	}
+	return report.Write(os.Stdout, users, offenders, scores)
+}
+
 // GetIllegalLoginUsersAndIp
 // Main purpose is to detect employees who have not logged in from office for 30days
 func getIllegalLoginUsersAndIHogep2(activities []*admin.Activity, officeIPs []string) []*admin.Activity {
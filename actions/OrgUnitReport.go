@@ -0,0 +1,120 @@
+package actions
+
+import (
+	"github.com/ken5scal/gsuite_toolkit/utilities"
+	"google.golang.org/api/admin/directory/v1"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OrgUnitReport aggregates users found by a user-centric report, such as users without 2SV, per org unit.
+// With Rollup, users in sub org units are also counted in their ancestors.
+// Top is number of worst offenders listed for each org unit.
+type OrgUnitReport struct {
+	Rollup bool
+	Top    int
+	Format string
+}
+
+// OrgUnitStat is aggregated result of an org unit
+type OrgUnitStat struct {
+	OrgUnitPath string   `json:"org_unit_path"`
+	Users       int      `json:"users"`
+	Offenders   int      `json:"offenders"`
+	Percentage  float64  `json:"percentage"`
+	Worst       []string `json:"worst"`
+}
+
+// Aggregate counts users and offenders per org unit.
+// scores ranks offenders by email, higher is worse. Offenders are ranked by email if scores is nil.
+func (r *OrgUnitReport) Aggregate(users, offenders []*admin.User, scores map[string]float64) []*OrgUnitStat {
+	stats := make(map[string]*OrgUnitStat)
+	stat := func(path string) *OrgUnitStat {
+		if _, ok := stats[path]; !ok {
+			stats[path] = &OrgUnitStat{OrgUnitPath: path}
+		}
+		return stats[path]
+	}
+
+	for _, u := range users {
+		for _, path := range r.targetPaths(u.OrgUnitPath) {
+			stat(path).Users++
+		}
+	}
+
+	ranked := append([]*admin.User{}, offenders...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		si, sj := scores[ranked[i].PrimaryEmail], scores[ranked[j].PrimaryEmail]
+		if si != sj {
+			return si > sj
+		}
+		return ranked[i].PrimaryEmail < ranked[j].PrimaryEmail
+	})
+	for _, u := range ranked {
+		for _, path := range r.targetPaths(u.OrgUnitPath) {
+			s := stat(path)
+			s.Offenders++
+			if len(s.Worst) < r.Top {
+				s.Worst = append(s.Worst, u.PrimaryEmail)
+			}
+		}
+	}
+
+	var result []*OrgUnitStat
+	for _, s := range stats {
+		if s.Users > 0 {
+			s.Percentage = float64(s.Offenders) * 100 / float64(s.Users)
+		}
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].OrgUnitPath < result[j].OrgUnitPath })
+	return result
+}
+
+// Write aggregates users and writes the result in Format
+func (r *OrgUnitReport) Write(w io.Writer, users, offenders []*admin.User, scores map[string]float64) error {
+	if err := utilities.ValidateOutputFormat(r.Format); err != nil {
+		return err
+	}
+
+	stats := r.Aggregate(users, offenders, scores)
+	if r.Format == utilities.JSON {
+		return utilities.WriteJSON(w, stats)
+	}
+
+	var rows [][]string
+	for _, s := range stats {
+		rows = append(rows, []string{
+			s.OrgUnitPath,
+			strconv.Itoa(s.Users),
+			strconv.Itoa(s.Offenders),
+			strconv.FormatFloat(s.Percentage, 'f', 1, 64),
+			strings.Join(s.Worst, " "),
+		})
+	}
+	return utilities.WriteCSV(w, []string{"org_unit_path", "users", "offenders", "percentage", "worst"}, rows)
+}
+
+// targetPaths returns org units a user is counted in.
+// Example: "/CISO室/セキュリティ推進グループ" with Rollup -> "/", "/CISO室", "/CISO室/セキュリティ推進グループ"
+func (r *OrgUnitReport) targetPaths(path string) []string {
+	if path == "" {
+		path = "/"
+	}
+	if !r.Rollup {
+		return []string{path}
+	}
+
+	paths := []string{"/"}
+	current := ""
+	for _, name := range strings.Split(strings.Trim(path, "/"), "/") {
+		if name == "" {
+			continue
+		}
+		current += "/" + name
+		paths = append(paths, current)
+	}
+	return paths
+}
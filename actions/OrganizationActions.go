@@ -0,0 +1,264 @@
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ken5scal/gsuite_toolkit/models"
+	"github.com/ken5scal/gsuite_toolkit/services"
+	"github.com/ken5scal/gsuite_toolkit/utilities"
+	"google.golang.org/api/admin/directory/v1"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OrganizationAction
+type OrganizationAction struct {
+	*services.OrganizationService
+	user *services.UserService
+}
+
+// InitOrganizationAction initializes Organization Action
+func InitOrganizationAction() *OrganizationAction {
+	return &OrganizationAction{}
+}
+
+// SetService sets service in Action.
+// UserService is required to count users in org units.
+func (action *OrganizationAction) SetService(s services.Service) error {
+	switch s.(type) {
+	case *services.OrganizationService:
+		action.OrganizationService = s.(*services.OrganizationService)
+	case *services.UserService:
+		action.user = s.(*services.UserService)
+	default:
+		return errors.New(fmt.Sprintf("Invalid type: %T", s))
+	}
+	return nil
+}
+
+// orgUnitTree is org units indexed by path of their parent
+type orgUnitTree map[string][]*admin.OrgUnit
+
+func (action OrganizationAction) getOrgUnitTree() (orgUnitTree, error) {
+	units, err := action.OrganizationService.GetAllOrganizationUnits()
+	if err != nil {
+		return nil, err
+	}
+	tree := make(orgUnitTree)
+	for _, u := range units.OrganizationUnits {
+		tree[u.ParentOrgUnitPath] = append(tree[u.ParentOrgUnitPath], u)
+	}
+	for _, children := range tree {
+		sort.Slice(children, func(i, j int) bool { return children[i].Name < children[j].Name })
+	}
+	return tree, nil
+}
+
+// countUsers counts users directly belonging to each org unit. Users of every domain are counted,
+// since an org unit may hold users of secondary domains.
+func (action OrganizationAction) countUsers() (map[string]int, error) {
+	users, err := action.user.GetAllEmployees()
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, u := range users {
+		counts[u.OrgUnitPath]++
+	}
+	return counts, nil
+}
+
+// ShowTree prints hierarchy of org units with number of users.
+// Each line shows users directly in the org unit and, in parenthesis, users including sub org units.
+func (action OrganizationAction) ShowTree() error {
+	if action.user == nil {
+		return errors.New("UserService must be set")
+	}
+	tree, err := action.getOrgUnitTree()
+	if err != nil {
+		return err
+	}
+	counts, err := action.countUsers()
+	if err != nil {
+		return err
+	}
+
+	type line struct {
+		label string
+		path  string
+	}
+	lines := []line{{"/", "/"}}
+	var walk func(parent, indent string)
+	walk = func(parent, indent string) {
+		children := tree[parent]
+		for i, u := range children {
+			branch, next := "├── ", "│   "
+			if i == len(children)-1 {
+				branch, next = "└── ", "    "
+			}
+			lines = append(lines, line{indent + branch + u.Name, u.OrgUnitPath})
+			walk(u.OrgUnitPath, indent+next)
+		}
+	}
+	walk("/", "")
+
+	var total func(path string) int
+	total = func(path string) int {
+		n := counts[path]
+		for _, u := range tree[path] {
+			n += total(u.OrgUnitPath)
+		}
+		return n
+	}
+
+	width := 0
+	for _, l := range lines {
+		if w := utilities.DisplayWidth(l.label); w > width {
+			width = w
+		}
+	}
+	for _, l := range lines {
+		fmt.Println(utilities.PadRight(l.label, width) + "  " +
+			strconv.Itoa(counts[l.path]) + " (" + strconv.Itoa(total(l.path)) + ")")
+	}
+	return nil
+}
+
+// CreateOrgUnit creates an org unit together with its missing parents.
+// Outcome of each creation is written to stderr as CSV.
+func (action OrganizationAction) CreateOrgUnit(path string) error {
+	created, result := action.OrganizationService.CreateOrganizationUnitPath(path)
+	for _, u := range created {
+		fmt.Println("Created " + u.OrgUnitPath)
+	}
+	if err := result.Write(os.Stderr, utilities.CSV); err != nil {
+		return err
+	}
+	if err := result.Err(); err != nil {
+		return err
+	}
+	if len(created) == 0 {
+		fmt.Println(path + " already exists")
+	}
+	return nil
+}
+
+// RenameOrgUnit renames an org unit
+func (action OrganizationAction) RenameOrgUnit(path, name string) error {
+	u, err := action.OrganizationService.RenameOrganizationUnit(path, name)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Renamed " + path + " to " + u.OrgUnitPath)
+	return nil
+}
+
+// MoveOrgUnit moves an org unit under another parent
+func (action OrganizationAction) MoveOrgUnit(path, parent string) error {
+	u, err := action.OrganizationService.MoveOrganizationUnit(path, parent)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Moved " + path + " to " + u.OrgUnitPath)
+	return nil
+}
+
+// DeleteOrgUnit deletes an org unit after checking it has neither users nor child org units
+func (action OrganizationAction) DeleteOrgUnit(path string) error {
+	if action.user == nil {
+		return errors.New("UserService must be set")
+	}
+	path = "/" + strings.Trim(path, "/")
+	if path == "/" {
+		return errors.New("Root org unit can not be deleted")
+	}
+
+	tree, err := action.getOrgUnitTree()
+	if err != nil {
+		return err
+	}
+	if children := tree[path]; len(children) > 0 {
+		return errors.New(fmt.Sprintf("%v has %d child org units", path, len(children)))
+	}
+	counts, err := action.countUsers()
+	if err != nil {
+		return err
+	}
+	if n := counts[path]; n > 0 {
+		return errors.New(fmt.Sprintf("%v has %d users", path, n))
+	}
+
+	if err = action.OrganizationService.DeleteOrganizationUnit(path); err != nil {
+		return err
+	}
+	fmt.Println("Deleted " + path)
+	return nil
+}
+
+// SyncOrgUnits compares org units in specFile with existing ones and prints changes.
+// With apply, it also carries out changes and writes outcome of each change to stderr as CSV.
+// Extra org units are deleted only with deleteExtra.
+func (action OrganizationAction) SyncOrgUnits(specFile string, deleteExtra, apply bool) error {
+	specs, err := models.LoadOrgUnitSpecs(specFile)
+	if err != nil {
+		return err
+	}
+
+	changes, err := action.OrganizationService.PlanOrganizationUnits(flattenOrgUnitSpecs(specs, "/"), deleteExtra)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Println("No changes.")
+		return nil
+	}
+
+	applicable := 0
+	for _, c := range changes {
+		switch c.Kind {
+		case services.OrgUnitCreate:
+			fmt.Println("+ " + c.Path)
+		case services.OrgUnitUpdate:
+			fmt.Printf("~ %v (description: %q -> %q, block_inheritance: %v -> %v)\n", c.Path,
+				c.Current.Description, c.Desired.Description, c.Current.BlockInheritance, c.Desired.BlockInheritance)
+		case services.OrgUnitDelete:
+			fmt.Println("- " + c.Path)
+		case services.OrgUnitExtra:
+			fmt.Println("? " + c.Path + " (not in " + specFile + ")")
+			continue
+		}
+		applicable++
+	}
+
+	if !apply || applicable == 0 {
+		return nil
+	}
+	result := action.OrganizationService.ApplyOrganizationUnits(changes)
+	if err = result.Write(os.Stderr, utilities.CSV); err != nil {
+		return err
+	}
+	if err = result.Err(); err != nil {
+		return err
+	}
+	fmt.Printf("Applied %d changes.\n", applicable)
+	return nil
+}
+
+// flattenOrgUnitSpecs converts hierarchy of specs into org units with full paths, parent first.
+func flattenOrgUnitSpecs(specs []models.OrgUnitSpec, parent string) []*admin.OrgUnit {
+	var units []*admin.OrgUnit
+	for _, spec := range specs {
+		path := strings.TrimSuffix(parent, "/") + "/" + spec.Name
+		units = append(units, &admin.OrgUnit{
+			Name:              spec.Name,
+			OrgUnitPath:       path,
+			ParentOrgUnitPath: parent,
+			Description:       spec.Description,
+			BlockInheritance:  spec.BlockInheritance,
+		})
+		units = append(units, flattenOrgUnitSpecs(spec.Children, path)...)
+	}
+	return units
+}
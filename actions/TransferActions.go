@@ -0,0 +1,154 @@
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ken5scal/gsuite_toolkit/services"
+	"github.com/ken5scal/gsuite_toolkit/utilities"
+	datatransfer "google.golang.org/api/admin/datatransfer/v1"
+	"os"
+	"strings"
+	"time"
+)
+
+// TransferAction transfers data of departing users to other users
+type TransferAction struct {
+	*services.DataTransferService
+}
+
+// InitTransferAction initializes Transfer Action
+func InitTransferAction() *TransferAction {
+	return &TransferAction{}
+}
+
+// SetService sets service in Action
+func (action *TransferAction) SetService(s services.Service) error {
+	if _, ok := s.(*services.DataTransferService); !ok {
+		return errors.New(fmt.Sprintf("Invalid type: %T", s))
+	}
+	action.DataTransferService = s.(*services.DataTransferService)
+	return nil
+}
+
+// Transfer starts transfer of applications from a user to another.
+// With wait, it polls the transfer every interval until done or timeout passes.
+func (action TransferAction) Transfer(from, to string, applications []string, wait bool, interval, timeout time.Duration) error {
+	transfer, err := action.DataTransferService.StartTransfer(from, to, applications)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Started transfer %v: %v -> %v\n", transfer.Id, from, to)
+	if !wait {
+		return nil
+	}
+	return action.Wait(transfer.Id, interval, timeout)
+}
+
+// BulkTransfer starts transfers of pairs listed in csvFile (from,to). If wait is true, it then polls all of them together
+// every interval until each is done or timeout passes.
+func (action TransferAction) BulkTransfer(csvFile string, applications []string, wait bool, interval, timeout time.Duration) error {
+	pairs, err := readPairCSV(csvFile, "from")
+	if err != nil {
+		return err
+	}
+
+	var ids []string
+	froms := make(map[string]string)
+	result := services.NewBulkResult("transfer data of")
+	for _, p := range pairs {
+		transfer, err := action.DataTransferService.StartTransfer(p[0], p[1], applications)
+		if err != nil {
+			result.Add(p[0], err)
+			continue
+		}
+		fmt.Printf("Started transfer %v: %v -> %v\n", transfer.Id, p[0], p[1])
+		if wait {
+			ids = append(ids, transfer.Id)
+			froms[transfer.Id] = p[0]
+		} else {
+			result.Add(p[0], nil)
+		}
+	}
+
+	if len(ids) > 0 {
+		_, errs := action.DataTransferService.WaitTransfers(ids, interval, timeout)
+		for _, id := range ids {
+			result.Add(froms[id], errs[id])
+		}
+	}
+	return result.Err()
+}
+
+// Wait polls a transfer every interval until done or timeout passes, then prints its result
+func (action TransferAction) Wait(id string, interval, timeout time.Duration) error {
+	transfer, err := action.DataTransferService.WaitTransfer(id, interval, timeout)
+	if transfer != nil {
+		if e := action.writeTransfers(utilities.CSV, []*datatransfer.DataTransfer{transfer}); e != nil {
+			return e
+		}
+	}
+	return err
+}
+
+// ListTransfers lists transfers of status, or all transfers if status is empty
+func (action TransferAction) ListTransfers(status, format string) error {
+	if err := utilities.ValidateOutputFormat(format); err != nil {
+		return err
+	}
+	transfers, err := action.DataTransferService.GetTransfers(status)
+	if err != nil {
+		return err
+	}
+	return action.writeTransfers(format, transfers)
+}
+
+// TransferSummary is a transfer with emails and names of applications instead of IDs
+type TransferSummary struct {
+	Id           string            `json:"id"`
+	From         string            `json:"from"`
+	To           string            `json:"to"`
+	Status       string            `json:"status"`
+	RequestTime  string            `json:"request_time"`
+	Applications map[string]string `json:"applications"`
+}
+
+func (action TransferAction) writeTransfers(format string, transfers []*datatransfer.DataTransfer) error {
+	emails := make(map[string]string)
+	email := func(id string) (string, error) {
+		if _, ok := emails[id]; !ok {
+			e, err := action.DataTransferService.GetUserEmail(id)
+			if err != nil {
+				return "", err
+			}
+			emails[id] = e
+		}
+		return emails[id], nil
+	}
+
+	var summaries []*TransferSummary
+	var rows [][]string
+	for _, t := range transfers {
+		from, err := email(t.OldOwnerUserId)
+		if err != nil {
+			return err
+		}
+		to, err := email(t.NewOwnerUserId)
+		if err != nil {
+			return err
+		}
+		summary := &TransferSummary{t.Id, from, to, t.OverallTransferStatusCode, t.RequestTime, make(map[string]string)}
+		var applications []string
+		for _, a := range t.ApplicationDataTransfers {
+			name := action.DataTransferService.GetApplicationName(a.ApplicationId)
+			summary.Applications[name] = a.ApplicationTransferStatus
+			applications = append(applications, name+":"+a.ApplicationTransferStatus)
+		}
+		summaries = append(summaries, summary)
+		rows = append(rows, []string{t.Id, from, to, summary.Status, summary.RequestTime, strings.Join(applications, " ")})
+	}
+
+	if format == utilities.JSON {
+		return utilities.WriteJSON(os.Stdout, summaries)
+	}
+	return utilities.WriteCSV(os.Stdout, []string{"id", "from", "to", "status", "request_time", "applications"}, rows)
+}
@@ -0,0 +1,296 @@
+package actions
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/ken5scal/gsuite_toolkit/services"
+	"github.com/ken5scal/gsuite_toolkit/utilities"
+	"google.golang.org/api/admin/directory/v1"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// UserAction
+type UserAction struct {
+	*services.UserService
+	org *services.OrganizationService
+}
+
+// OrgUnitMove is a move of a user from an org unit to another
+type OrgUnitMove struct {
+	Email string
+	From  string
+	To    string
+}
+
+// InitUserAction initializes User Action
+func InitUserAction() *UserAction {
+	return &UserAction{}
+}
+
+// SetService sets service in Action.
+// OrganizationService is required to validate org units.
+func (action *UserAction) SetService(s services.Service) error {
+	switch s.(type) {
+	case *services.UserService:
+		action.UserService = s.(*services.UserService)
+	case *services.OrganizationService:
+		action.org = s.(*services.OrganizationService)
+	default:
+		return errors.New(fmt.Sprintf("Invalid type: %T", s))
+	}
+	return nil
+}
+
+// MoveOrgUnits moves users listed in csvFile (email,orgUnitPath), or users matching filter to an org unit.
+// It validates all target org units exist and prints a summary before moving users.
+// Original org units are recorded in rollbackFile, which can be passed as csvFile to undo the moves.
+// Users of every domain are looked up, and users in csvFile may be given by their aliases.
+// Moves run concurrently by parallel workers.
+func (action UserAction) MoveOrgUnits(csvFile, filter, to, rollbackFile string, parallel int, dryRun bool) error {
+	if action.org == nil {
+		return errors.New("OrganizationService must be set")
+	} else if (csvFile == "") == (filter == "") {
+		return errors.New("Specify either CSV file or filter")
+	} else if filter != "" && to == "" {
+		return errors.New("Specify org unit to move users matching filter")
+	} else if parallel < 1 {
+		return errors.New("parallel must be positive")
+	}
+
+	users, err := action.UserService.GetAllEmployees()
+	if err != nil {
+		return err
+	}
+	current := make(map[string]*admin.User, len(users))
+	for _, u := range users {
+		for _, email := range append([]string{u.PrimaryEmail}, append(u.Aliases, u.NonEditableAliases...)...) {
+			current[strings.ToLower(email)] = u
+		}
+	}
+
+	var moves []*OrgUnitMove
+	if csvFile != "" {
+		targets, err := readPairCSV(csvFile, "email")
+		if err != nil {
+			return err
+		}
+		for _, t := range targets {
+			u, ok := current[strings.ToLower(t[0])]
+			if !ok {
+				return errors.New(fmt.Sprintf("User not found: %v", t[0]))
+			}
+			moves = append(moves, &OrgUnitMove{u.PrimaryEmail, u.OrgUnitPath, normalizeOrgUnitPath(t[1])})
+		}
+	} else {
+		rule, err := utilities.ParseFilter(filter)
+		if err != nil {
+			return err
+		}
+		for _, u := range users {
+			if rule.Match(services.GetUserAttributes(u)) {
+				moves = append(moves, &OrgUnitMove{u.PrimaryEmail, u.OrgUnitPath, normalizeOrgUnitPath(to)})
+			}
+		}
+	}
+
+	units, err := action.org.GetAllOrganizationUnits()
+	if err != nil {
+		return err
+	}
+	exists := map[string]bool{"/": true}
+	for _, u := range units.OrganizationUnits {
+		exists[u.OrgUnitPath] = true
+	}
+
+	var missing []string
+	var pending []*OrgUnitMove
+	for _, m := range moves {
+		if !exists[m.To] {
+			missing = append(missing, m.To)
+		} else if m.From != m.To {
+			pending = append(pending, m)
+		}
+	}
+	if len(missing) > 0 {
+		return errors.New("Org units not found: " + strings.Join(missing, ", "))
+	}
+
+	printMoveSummary(pending)
+	if dryRun || len(pending) == 0 {
+		return nil
+	}
+
+	if err = writeRollbackFile(rollbackFile, pending); err != nil {
+		return err
+	}
+	fmt.Println("Original org units are recorded in " + rollbackFile)
+
+	type outcome struct {
+		move *OrgUnitMove
+		err  error
+	}
+	jobs := make(chan *OrgUnitMove)
+	outcomes := make(chan outcome)
+	var wg sync.WaitGroup
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for m := range jobs {
+				_, err := action.UserService.PatchOrgUnit(m.Email, m.To)
+				outcomes <- outcome{m, err}
+			}
+		}()
+	}
+	go func() {
+		for _, m := range pending {
+			jobs <- m
+		}
+		close(jobs)
+		wg.Wait()
+		close(outcomes)
+	}()
+
+	result := services.NewBulkResult("move user")
+	for o := range outcomes {
+		result.Add(o.move.Email, o.err)
+	}
+	fmt.Printf("Moved %d of %d users\n", len(result.Items)-len(result.Failures()), len(pending))
+	return result.Err()
+}
+
+// printMoveSummary prints number of users for each pair of org units, followed by each user
+func printMoveSummary(moves []*OrgUnitMove) {
+	counts := make(map[string]int)
+	for _, m := range moves {
+		counts[m.From+" -> "+m.To]++
+	}
+	var pairs []string
+	for pair := range counts {
+		pairs = append(pairs, pair)
+	}
+	sort.Strings(pairs)
+
+	fmt.Printf("%d users to move\n", len(moves))
+	for _, pair := range pairs {
+		fmt.Printf("	%v: %d\n", pair, counts[pair])
+	}
+	for _, m := range moves {
+		fmt.Println("	" + m.Email + ": " + m.From + " -> " + m.To)
+	}
+}
+
+// Columns of UserDataTmpl.csv set to organization of imported users, keyed by their names
+var importOrganizationColumns = map[string]string{
+	"Employee Title": "title",
+	"Employee Type":  "description",
+	"Department":     "department",
+	"Cost Center":    "costCenter",
+}
+
+// ImportUsers creates users listed in csvFile in the format of UserDataTmpl.csv.
+// First Name, Last Name, Email Address and Password are required, and Employee Title, Employee Type, Department and
+// Cost Center are set to organization of users. Other columns are ignored.
+// Failures do not stop the import and are returned together as *services.BulkResult.
+// Outcome of each user is written to stderr as CSV. With dryRun, it only prints users to create.
+func (action UserAction) ImportUsers(csvFile string, dryRun bool) error {
+	f, err := os.Open(csvFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return err
+	} else if len(rows) == 0 {
+		return errors.New(fmt.Sprintf("%v is empty", csvFile))
+	}
+
+	columns := make(map[string]int)
+	for i, name := range rows[0] {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, name := range []string{"First Name", "Last Name", "Email Address", "Password"} {
+		if _, ok := columns[name]; !ok {
+			return errors.New(fmt.Sprintf("Column %v is missing in %v", name, csvFile))
+		}
+	}
+	value := func(row []string, name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	result := services.NewBulkResult("import user")
+	for _, row := range rows[1:] {
+		email := value(row, "Email Address")
+		organization := make(map[string]string)
+		for column, key := range importOrganizationColumns {
+			if v := value(row, column); v != "" {
+				organization[key] = v
+			}
+		}
+		fmt.Println("create " + email)
+		if dryRun {
+			continue
+		}
+		_, err := action.UserService.CreateUser(value(row, "Last Name"), value(row, "First Name"), email,
+			value(row, "Password"), organization)
+		result.Add(email, err)
+	}
+	if err = result.Write(os.Stderr, utilities.CSV); err != nil {
+		return err
+	}
+	return result.Err()
+}
+
+// readPairCSV reads rows of two columns, such as pairs of email and org unit path.
+// The first row is skipped as header if its first column is header.
+func readPairCSV(path, header string) ([][]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = 2
+	var rows [][]string
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if len(rows) == 0 && strings.EqualFold(row[0], header) {
+			continue
+		}
+		rows = append(rows, row)
+	}
+}
+
+// writeRollbackFile records original org units in the same format as MoveOrgUnits reads
+func writeRollbackFile(path string, moves []*OrgUnitMove) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var rows [][]string
+	for _, m := range moves {
+		rows = append(rows, []string{m.Email, m.From})
+	}
+	return utilities.WriteCSV(f, []string{"email", "orgUnitPath"}, rows)
+}
+
+func normalizeOrgUnitPath(path string) string {
+	return "/" + strings.Trim(strings.TrimSpace(path), "/")
+}
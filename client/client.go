@@ -21,6 +21,8 @@ type ClientConfig struct {
 	clientSecretFileName string
 	scopes               []string
 	domainName string
+	serviceAccountKeyFileName string
+	subject string
 }
 
 func CreateConfig() *ClientConfig {
@@ -42,8 +44,26 @@ func (config *ClientConfig) setDomain(domainName string) *ClientConfig {
 	return config
 }
 
+// SetServiceAccountKeyFilename makes Build use a service account instead of the authorized user.
+// Domain-wide delegation must be granted to the service account to impersonate users.
+// https://developers.google.com/admin-sdk/directory/v1/guides/delegation
+func (config *ClientConfig) SetServiceAccountKeyFilename(keyFileName string) *ClientConfig {
+	config.serviceAccountKeyFileName = keyFileName
+	return config
+}
+
+// SetSubject sets email of user the service account impersonates
+func (config *ClientConfig) SetSubject(email string) *ClientConfig {
+	config.subject = email
+	return config
+}
+
 // Build Generate New Client
 func (config *ClientConfig) Build() (*http.Client, error) {
+	if config.serviceAccountKeyFileName != "" {
+		return config.buildServiceAccountClient()
+	}
+
 	b, err := ioutil.ReadFile(config.clientSecretFileName)
 	if err != nil {
 		return nil, errors.New(fmt.Sprintf("Unable to read client secret file: %v", err))
@@ -60,6 +80,21 @@ func (config *ClientConfig) Build() (*http.Client, error) {
 	return c.Client(context.Background(), token), nil
 }
 
+// buildServiceAccountClient generates a client authorized by service account key, impersonating subject
+func (config *ClientConfig) buildServiceAccountClient() (*http.Client, error) {
+	b, err := ioutil.ReadFile(config.serviceAccountKeyFileName)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to read service account key file: %v", err))
+	}
+
+	c, err := google.JWTConfigFromJSON(b, config.scopes...)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to parse service account key file to config: %v", err))
+	}
+	c.Subject = config.subject
+	return c.Client(context.Background()), nil
+}
+
 func getToken(config *oauth2.Config) *oauth2.Token {
 	cacheFile, err := tokenCacheFile()
 	if err != nil {
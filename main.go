@@ -2,7 +2,6 @@ package main
 
 import (
 	"errors"
-	"fmt"
 	"github.com/BurntSushi/toml"
 	"github.com/ken5scal/gsuite_toolkit/actions"
 	"github.com/ken5scal/gsuite_toolkit/client"
@@ -12,12 +11,19 @@ import (
 	"net/http"
 	"os"
 	"sort"
+	"strings"
+	"time"
 	"github.com/ken5scal/gsuite_toolkit/services"
+	"github.com/ken5scal/gsuite_toolkit/utilities"
 	"github.com/asaskevich/govalidator"
+	"google.golang.org/api/drive/v3"
 )
 
 const (
 	ClientSecretFileName = "client_secret.json"
+	GrantsFileName       = "gsuite_grants.json"
+	OrgUnitsFileName     = "gsuite_orgunits.yml"
+	AuditArchiveDirName  = "gsuite_audit_archive"
 )
 
 type network struct {
@@ -32,6 +38,9 @@ func main() {
 	var gsuiteClient *http.Client
 
 	_, err := toml.DecodeFile("gsuite_config.toml", &tomlConf)
+	if err == nil {
+		err = tomlConf.Validate()
+	}
 	if err != nil {
 		log.Fatalf("error: %v", err)
 	}
@@ -43,6 +52,56 @@ func main() {
 		return nil
 	}
 
+	// Flags of user-centric reports which can be aggregated per org unit
+	orgUnitReportFlags := []cli.Flag{
+		cli.BoolFlag{Name: "by-ou", Usage: "aggregate users per org unit"},
+		cli.BoolFlag{Name: "rollup", Usage: "with --by-ou, count users in sub org units in their parents as well"},
+		cli.IntFlag{Name: "top", Value: 5, Usage: "with --by-ou, number of worst offenders listed for each org unit"},
+		cli.StringFlag{Name: "format", Value: utilities.CSV, Usage: "with --by-ou, output format: csv or json"},
+	}
+	getOrgUnitReport := func(c *cli.Context) *actions.OrgUnitReport {
+		if !c.Bool("by-ou") {
+			return nil
+		}
+		return &actions.OrgUnitReport{Rollup: c.Bool("rollup"), Top: c.Int("top"), Format: c.String("format")}
+	}
+
+	// Flags of time windows of audit commands
+	auditWindowFlags := []cli.Flag{
+		cli.StringFlag{Name: "since", Usage: "from this time: RFC3339, date such as 2017-08-01, duration such as 7d, 12h or 30m " +
+			"(w, d, h and m are weeks, days, hours and minutes, not months), or period such as this-week, last-month, last-quarter or fiscal-year"},
+		cli.StringFlag{Name: "until", Usage: "until this time in the same format as --since. A date includes the whole day. " +
+			"Defaults to now, or end of period given by --since"},
+		cli.StringFlag{Name: "tz", Usage: "timezone of dates and periods such as Asia/Tokyo. Defaults to timezone in config, or local timezone"},
+	}
+	getTimeParser := func(c *cli.Context) (*utilities.TimeParser, error) {
+		tz := c.String("tz")
+		if tz == "" {
+			tz = tomlConf.Audit.Timezone
+		}
+		return utilities.NewTimeParser(tz, tomlConf.Audit.FiscalYearStart)
+	}
+	getAuditWindow := func(c *cli.Context) (*utilities.TimeWindow, error) {
+		parser, err := getTimeParser(c)
+		if err != nil {
+			return nil, err
+		}
+		window, err := parser.ParseWindow(c.String("since"), c.String("until"))
+		if err != nil {
+			return nil, err
+		} else if c.GlobalBool("archive") {
+			return window, nil
+		}
+		return window, window.CheckRetention(parser.Now)
+	}
+	openAuditArchive := func() (*services.AuditArchive, error) {
+		dir := tomlConf.Audit.ArchiveDir
+		if dir == "" {
+			dir = AuditArchiveDirName
+		}
+		return services.OpenAuditArchive(dir)
+	}
+
 	setServiceToAction := func(s services.Service, a actions.Action) error {
 		if err := a.SetService(s); err != nil {
 			return err
@@ -62,32 +121,96 @@ func main() {
 		SetScopes(tomlConf.Scopes).
 		Build()
 	if err != nil {
-		fmt.Errorf("Failed building client: %v", err)
-		return
+		log.Fatalf("Failed building client: %v", err)
 	}
 	app.Commands = []cli.Command{
 		{
 			Name: "audit", Category: "audit",
 			Usage: "Audit hogehoge",
+			Flags: []cli.Flag{
+				cli.BoolFlag{Name: "archive", Usage: "read activities from local archive synced by `audit sync` instead of Reports API"},
+			},
 			Before: func(context *cli.Context) error {
 				service = services.InitAuditService()
 				if err = service.SetClient(gsuiteClient); err != nil {
-					return nil
+					return err
+				}
+				if context.Bool("archive") {
+					archive, err := openAuditArchive()
+					if err != nil {
+						return err
+					}
+					service.(*services.AuditActivitiesService).SetArchive(archive)
 				}
 				action = actions.InitAuditAction()
 				return setServiceToAction(service, action)
 			},
 			Subcommands: []cli.Command {
 				{
-					Name: "user_created",
+					Name: "sync", Usage: "pull activities newer than the last sync into local archive",
+					Flags: []cli.Flag{
+						cli.StringSliceFlag{Name: "app", Usage: "application to sync. All of " + strings.Join(services.AuditApplications, ", ") + " if omitted"},
+						cli.DurationFlag{Name: "overlap", Value: 24 * time.Hour, Usage: "re-pull this duration before the last archived activity, for activities reported late"},
+					},
+					Action: func(context *cli.Context) error {
+						if context.GlobalBool("archive") {
+							return errors.New("--archive can't be used with sync, which always pulls from Reports API.")
+						}
+						archive, err := openAuditArchive()
+						if err != nil {
+							return err
+						}
+						return action.(*actions.AuditAction).SyncArchive(archive, context.StringSlice("app"), context.Duration("overlap"))
+					},
+				},
+				{
+					Name: "query", Usage: "list any activities of Reports API with their parameters as columns",
+					Flags: append([]cli.Flag{
+						cli.StringFlag{Name: "app", Usage: strings.Join(services.AuditApplications, ", ")},
+						cli.StringFlag{Name: "event", Usage: "event name. ex) CREATE_USER, login_failure. All events if omitted"},
+						cli.StringFlag{Name: "filter", Usage: "filters of event parameters. ex) login_type==google_password,is_suspicious==true"},
+						cli.StringFlag{Name: "actor", Value: "all", Usage: "email of actor, or all"},
+						cli.StringFlag{Name: "format", Value: utilities.CSV, Usage: "output format: csv or json"},
+					}, auditWindowFlags...),
 					Action: func(context *cli.Context) error {
-						return action.(*actions.AuditAction).GetCreatedUserInLastMonth()
+						if context.String("app") == "" {
+							return errors.New("Specify application by --app.")
+						}
+						window, err := getAuditWindow(context)
+						if err != nil {
+							return err
+						}
+						query := &services.ActivityQuery{
+							Actor:       context.String("actor"),
+							Application: context.String("app"),
+							Event:       context.String("event"),
+							Filters:     context.String("filter"),
+							Since:       window.Since,
+							Until:       window.Until,
+						}
+						return action.(*actions.AuditAction).Query(query, context.String("format"))
 					},
 				},
 				{
-					Name: "privileges_granted",
+					Name: "user_created", Usage: "list users created since the first day of last month, or within --since and --until",
+					Flags: auditWindowFlags,
 					Action: func(context *cli.Context) error {
-						return action.(*actions.AuditAction).GetAllGrantedPrivilegesUsersInLastMonth()
+						window, err := getAuditWindow(context)
+						if err != nil {
+							return err
+						}
+						return action.(*actions.AuditAction).GetCreatedUsers(window)
+					},
+				},
+				{
+					Name: "privileges_granted", Usage: "list users granted admin privileges since the first day of last month, or within --since and --until",
+					Flags: auditWindowFlags,
+					Action: func(context *cli.Context) error {
+						window, err := getAuditWindow(context)
+						if err != nil {
+							return err
+						}
+						return action.(*actions.AuditAction).GetAllGrantedPrivilegesUsers(window)
 					},
 				},
 				{
@@ -101,18 +224,46 @@ func main() {
 						}
 						s := services.InitUserService()
 						if err = s.SetClient(gsuiteClient); err != nil {
-							return nil
+							return err
 						}
 						if err != setServiceToAction(s, action) {
 							return err
 						}
-						return action.(*actions.LoginAction).GetIllegalLoginUsersAndIp2(tomlConf.GetAllIps())
+						window, err := getAuditWindow(c)
+						if err != nil {
+							return err
+						}
+						return action.(*actions.LoginAction).GetIllegalLoginUsersAndIp2(
+							tomlConf.GetAllIps(), tomlConf.Owner.Domain, window, getOrgUnitReport(c))
 					},
+					Flags: append(append([]cli.Flag{}, orgUnitReportFlags...), auditWindowFlags...),
 				},
 				{
 					Name:  "rare-login", Usage: "get employees who have not logged in for action while",
+					Flags: append([]cli.Flag{
+						cli.StringFlag{Name: "since", Value: "14d", Usage: "employees who have not logged in since this time, in the same format as other audit commands. " +
+							"m of durations is minutes, not months"},
+						cli.StringFlag{Name: "tz", Usage: "timezone of dates and periods such as Asia/Tokyo. Defaults to timezone in config, or local timezone"},
+					}, orgUnitReportFlags...),
 					Action: func(context *cli.Context) error {
-						return action.(*actions.LoginAction).GetUsersWithRareLogin(14, tomlConf.Owner.Domain)
+						parser, err := getTimeParser(context)
+						if err != nil {
+							return err
+						}
+						since, _, err := parser.Parse(context.String("since"))
+						if err != nil {
+							return err
+						}
+						action = actions.InitLoginAction()
+						s := services.InitUserService()
+						if err = s.SetClient(gsuiteClient); err != nil {
+							return err
+						}
+						if err = setServiceToAction(s, action); err != nil {
+							return err
+						}
+						return action.(*actions.LoginAction).GetUsersWithRareLogin(int(parser.Now.Sub(since).Hours()/24),
+							tomlConf.Owner.Domain, getOrgUnitReport(context))
 					},
 				},
 			},
@@ -123,7 +274,7 @@ func main() {
 			Before: func(context *cli.Context) error {
 				service = services.InitGroupService()
 				if err = service.SetClient(gsuiteClient); err != nil {
-					return nil
+					return err
 				}
 				action = actions.InitGroupAction()
 				return setServiceToAction(service, action)
@@ -161,6 +312,162 @@ func main() {
 						return action.(*actions.GroupAction).SearchGroupsByEmail(tomlConf.Owner.Domain, context.Args()[0])
 					},
 				},
+				{
+					Name: "member",
+					Usage: "add and remove members of a group",
+					Action: showHelpFunc,
+					Subcommands: []cli.Command{
+						{
+							Name: "add", Usage: "add a member to a group: member add <group email> <member email>",
+							Flags: []cli.Flag{
+								cli.StringFlag{Name: "role", Value: "MEMBER", Usage: "MEMBER, MANAGER or OWNER"},
+								cli.DurationFlag{Name: "expires", Usage: "remove the member by `group expire` after this duration. ex) 72h"},
+								cli.StringFlag{Name: "state", Value: GrantsFileName, Usage: "file recording memberships with expiry"},
+							},
+							Action: func(context *cli.Context) error {
+								if context.NArg() != 2 {
+									return errors.New("Specify group email and member email.")
+								} else if !govalidator.IsEmail(context.Args()[1]) {
+									return errors.New("Wrong email format.")
+								}
+								return action.(*actions.GroupAction).AddMember(context.Args()[0], context.Args()[1],
+									context.String("role"), context.Duration("expires"), context.String("state"))
+							},
+						},
+						{
+							Name: "remove", Usage: "remove a member from a group: member remove <group email> <member email>",
+							Action: func(context *cli.Context) error {
+								if context.NArg() != 2 {
+									return errors.New("Specify group email and member email.")
+								}
+								return action.(*actions.GroupAction).RemoveMember(context.Args()[0], context.Args()[1])
+							},
+						},
+					},
+				},
+				{
+					Name: "expire",
+					Usage: "remove expired memberships added with --expires and notify group owners. Suitable for cron.",
+					Flags: []cli.Flag{
+						cli.StringFlag{Name: "state", Value: GrantsFileName, Usage: "file recording memberships with expiry"},
+					},
+					Action: func(context *cli.Context) error {
+						s := services.InitMailService()
+						if err = s.SetClient(gsuiteClient); err != nil {
+							return err
+						}
+						if err = setServiceToAction(s, action); err != nil {
+							return err
+						}
+						return action.(*actions.GroupAction).ExpireMembers(context.String("state"))
+					},
+				},
+				{
+					Name: "alias",
+					Usage: "list, add and remove aliases of a group",
+					Action: showHelpFunc,
+					Subcommands: []cli.Command{
+						{
+							Name: "list", Usage: "list aliases of a group: alias list <group email>",
+							Action: func(context *cli.Context) error {
+								if context.NArg() != 1 {
+									return errors.New("Too few argument. Specify group email.")
+								}
+								return action.(*actions.GroupAction).ListAliases(context.Args()[0])
+							},
+						},
+						{
+							Name: "add", Usage: "add an alias to a group: alias add <group email> <alias>",
+							Action: func(context *cli.Context) error {
+								if context.NArg() != 2 {
+									return errors.New("Specify group email and alias.")
+								} else if !govalidator.IsEmail(context.Args()[1]) {
+									return errors.New("Wrong email format.")
+								}
+								s := services.InitUserService()
+								if err = s.SetClient(gsuiteClient); err != nil {
+									return err
+								}
+								if err = setServiceToAction(s, action); err != nil {
+									return err
+								}
+								return action.(*actions.GroupAction).AddAlias(context.Args()[0], context.Args()[1])
+							},
+						},
+						{
+							Name: "remove", Usage: "remove an alias from a group: alias remove <group email> <alias>",
+							Action: func(context *cli.Context) error {
+								if context.NArg() != 2 {
+									return errors.New("Specify group email and alias.")
+								}
+								return action.(*actions.GroupAction).RemoveAlias(context.Args()[0], context.Args()[1])
+							},
+						},
+					},
+				},
+				{
+					Name: "external",
+					Usage: "report groups containing members outside of own domains, grouped by group owner.",
+					Flags: []cli.Flag{
+						cli.StringFlag{Name: "format", Value: utilities.CSV, Usage: "output format: csv or json"},
+					},
+					Action: func(context *cli.Context) error {
+						return action.(*actions.GroupAction).ReportExternalMembers(tomlConf.GetAllDomains(), context.String("format"))
+					},
+				},
+				{
+					Name: "sync",
+					Usage: "synchronize members of dynamic groups defined in config with users matching their rules.",
+					Flags: []cli.Flag{
+						cli.BoolFlag{Name: "dry-run", Usage: "only print members to be added or removed"},
+					},
+					Action: func(context *cli.Context) error {
+						s := services.InitUserService()
+						if err = s.SetClient(gsuiteClient); err != nil {
+							return err
+						}
+						if err = setServiceToAction(s, action); err != nil {
+							return err
+						}
+						return action.(*actions.GroupAction).SyncDynamicGroups(
+							tomlConf.Owner.Domain, tomlConf.DynamicGroups, context.Bool("dry-run"))
+					},
+				},
+				{
+					Name: "hygiene",
+					Usage: "report groups without owners or activity, empty groups and groups with similar names.",
+					Flags: []cli.Flag{
+						cli.IntFlag{Name: "days", Value: 90, Usage: "days without activity to regard group as inactive (max 180)"},
+						cli.Float64Flag{Name: "similarity", Value: 0.85, Usage: "minimum similarity of names to regard groups as duplicates (0 to 1)"},
+						cli.StringFlag{Name: "format", Value: utilities.CSV, Usage: "output format: csv or json"},
+						cli.BoolFlag{Name: "archive", Usage: "read group activities from local archive synced by `audit sync` instead of Reports API"},
+					},
+					Action: func(context *cli.Context) error {
+						s := services.InitUserService()
+						if err = s.SetClient(gsuiteClient); err != nil {
+							return err
+						}
+						if err = setServiceToAction(s, action); err != nil {
+							return err
+						}
+						a := services.InitAuditService()
+						if err = a.SetClient(gsuiteClient); err != nil {
+							return err
+						}
+						if context.Bool("archive") {
+							archive, err := openAuditArchive()
+							if err != nil {
+								return err
+							}
+							a.SetArchive(archive)
+						}
+						if err = setServiceToAction(a, action); err != nil {
+							return err
+						}
+						return action.(*actions.GroupAction).CheckGroupHygiene(
+							tomlConf.Owner.Domain, context.Int("days"), context.Float64("similarity"), context.String("format"))
+					},
+				},
 			},
 		},
 		{
@@ -169,7 +476,7 @@ func main() {
 			Before: func(*cli.Context) error {
 				service = services.InitDriveService()
 				if err = service.SetClient(gsuiteClient); err != nil {
-					return nil
+					return err
 				}
 				action = actions.InitDriveAction()
 				return setServiceToAction(service, action)
@@ -183,12 +490,355 @@ func main() {
 					},
 				},
 				{
-					Name: "search", Usage: "search action keyword buy specifying an argument",
+					Name: "exposure", Usage: "list files shared publicly, to anyone with the link or outside of own domains. Files in shared drives are not included",
+					Flags: []cli.Flag{
+						cli.BoolFlag{Name: "impersonate", Usage: "audit Drive of every user by impersonating them with service account"},
+						cli.StringFlag{Name: "format", Value: utilities.CSV, Usage: "output format: csv or json"},
+					},
+					Action: func(context *cli.Context) error {
+						if !context.Bool("impersonate") {
+							return action.(*actions.DriveAction).AuditExposure(tomlConf.GetAllDomains(), nil, nil, context.String("format"))
+						}
+						owners, err := getActiveUserEmails(gsuiteClient, tomlConf.Owner.Domain)
+						if err != nil {
+							return err
+						}
+						return action.(*actions.DriveAction).AuditExposure(tomlConf.GetAllDomains(), owners,
+							impersonateDriveService(tomlConf.ServiceAccount.KeyFile, drive.DriveReadonlyScope), context.String("format"))
+					},
+				},
+				{
+					Name: "forms", Usage: "list Google Forms with their sharing and response spreadsheets. Forms in shared drives are not included",
+					Flags: []cli.Flag{
+						cli.BoolFlag{Name: "impersonate", Usage: "audit forms of every user by impersonating them with service account"},
+						cli.StringFlag{Name: "format", Value: utilities.CSV, Usage: "output format: csv or json"},
+					},
+					Action: func(context *cli.Context) error {
+						if !context.Bool("impersonate") {
+							return action.(*actions.DriveAction).AuditForms(tomlConf.GetAllDomains(), nil, nil, context.String("format"))
+						}
+						owners, err := getActiveUserEmails(gsuiteClient, tomlConf.Owner.Domain)
+						if err != nil {
+							return err
+						}
+						return action.(*actions.DriveAction).AuditForms(tomlConf.GetAllDomains(), owners,
+							impersonateDriveService(tomlConf.ServiceAccount.KeyFile, drive.DriveReadonlyScope), context.String("format"))
+					},
+				},
+				{
+					Name: "inventory", Usage: "list every file and folder with its full path and sharing summary in JSON Lines",
+					Flags: []cli.Flag{
+						cli.StringFlag{Name: "root", Value: "root", Usage: "ID of folder to start from. Defaults to My Drive"},
+						cli.IntFlag{Name: "parallel", Value: 4, Usage: "number of folders listed concurrently"},
+						cli.BoolTFlag{Name: "shared-drives", Usage: "walk shared drives you are a member of as well unless --root is given. Disable by --shared-drives=false"},
+					},
+					Action: func(context *cli.Context) error {
+						return action.(*actions.DriveAction).Inventory(os.Stdout, context.String("root"), tomlConf.GetAllDomains(),
+							context.Int("parallel"), context.BoolT("shared-drives") && !context.IsSet("root"))
+					},
+				},
+				{
+					Name: "orphans", Usage: "list shared files owned by suspended or deleted users, and hand them over to archive owner",
+					Flags: []cli.Flag{
+						cli.StringFlag{Name: "input", Usage: "inventory of the whole organization, such as outputs of `drive inventory` run as each user. " +
+							"Inventory of a single user misses files of the others. If omitted, files of suspended users visible to you are searched, " +
+							"and files of deleted users are not found"},
+						cli.BoolFlag{Name: "transfer", Usage: "transfer Drive of suspended owners by Data Transfer API"},
+						cli.StringFlag{Name: "to", Usage: "recipient of transfer. Defaults to archive_owner in config"},
+						cli.BoolFlag{Name: "dry-run", Usage: "with --transfer, only show transfers"},
+						cli.StringFlag{Name: "format", Value: utilities.CSV, Usage: "output format: csv or json"},
+					},
+					Action: func(context *cli.Context) error {
+						s := services.InitUserService()
+						if err = s.SetClient(gsuiteClient); err != nil {
+							return err
+						}
+						if err = setServiceToAction(s, action); err != nil {
+							return err
+						}
+
+						var to string
+						if context.Bool("transfer") {
+							if to = context.String("to"); to == "" {
+								to = tomlConf.Owner.ArchiveOwner
+							}
+							if to == "" {
+								return errors.New("Specify recipient by --to or archive_owner in config.")
+							}
+							t := services.InitDataTransferService()
+							if err = t.SetClient(gsuiteClient); err != nil {
+								return err
+							}
+							if err = setServiceToAction(t, action); err != nil {
+								return err
+							}
+						}
+						return action.(*actions.DriveAction).FindOrphanedFiles(context.String("input"), tomlConf.GetAllDomains(),
+							to, context.Bool("dry-run"), context.String("format"))
+					},
+				},
+				{
+					Name: "overshared", Usage: "list files granting access which their parent folders don't",
+					Flags: []cli.Flag{
+						cli.StringFlag{Name: "root", Value: "root", Usage: "ID of folder to start from. Defaults to My Drive"},
+						cli.BoolTFlag{Name: "recursive", Usage: "compare every file under root. Disable by --recursive=false to compare only files directly within root"},
+						cli.BoolTFlag{Name: "shared-drives", Usage: "walk shared drives you are a member of as well unless --root is given. Disable by --shared-drives=false"},
+						cli.IntFlag{Name: "parallel", Value: 4, Usage: "number of folders listed concurrently"},
+						cli.StringFlag{Name: "format", Value: utilities.CSV, Usage: "output format: csv or json"},
+					},
+					Action: func(context *cli.Context) error {
+						return action.(*actions.DriveAction).FindOversharing(context.String("root"), tomlConf.GetAllDomains(),
+							context.BoolT("recursive"), context.BoolT("shared-drives") && !context.IsSet("root"),
+							context.Int("parallel"), context.String("format"))
+					},
+				},
+				{
+					Name: "scan", Usage: "find files whose name or description matches sensitive patterns, rated by their sharing",
+					Flags: []cli.Flag{
+						cli.StringFlag{Name: "input", Usage: "output of `drive inventory`. My Drive and shared drives you are a member of are walked if omitted"},
+						cli.IntFlag{Name: "parallel", Value: 4, Usage: "number of folders listed concurrently"},
+						cli.StringFlag{Name: "min-risk", Value: actions.RiskLow, Usage: "none, low, medium or high"},
+						cli.StringFlag{Name: "format", Value: utilities.CSV, Usage: "output format: csv or json"},
+					},
+					Action: func(context *cli.Context) error {
+						rules, err := actions.CompileSensitiveRules(tomlConf.SensitivePatterns)
+						if err != nil {
+							return err
+						}
+						return action.(*actions.DriveAction).ScanSensitive(context.String("input"), tomlConf.GetAllDomains(),
+							context.Int("parallel"), rules, context.String("min-risk"), context.String("format"))
+					},
+				},
+				{
+					Name: "shared-drives", Usage: "list shared drives with their members, organizers and external members. " +
+						"Only shared drives you are a member of are listed, even if you are an administrator",
+					Flags: []cli.Flag{
+						cli.StringFlag{Name: "format", Value: utilities.CSV, Usage: "output format: csv or json"},
+					},
+					Action: func(context *cli.Context) error {
+						return action.(*actions.DriveAction).AuditSharedDrives(tomlConf.GetAllDomains(), context.String("format"))
+					},
+				},
+				{
+					Name: "watch", Usage: "poll changes of Drive and alert files newly shared publicly or outside of own domains",
+					Flags: []cli.Flag{
+						cli.StringFlag{Name: "state", Value: "gsuite_drive_watch.json", Usage: "file persisting page token and alerted permissions"},
+						cli.DurationFlag{Name: "interval", Value: time.Minute, Usage: "interval of polling"},
+						cli.BoolFlag{Name: "once", Usage: "poll only once, for example from cron"},
+						cli.StringFlag{Name: "impersonate", Usage: "watch Drive of this user with service account instead of the authorized user"},
+					},
+					Action: func(context *cli.Context) error {
+						var rule *utilities.Rule
+						if tomlConf.DriveWatch.Rule != "" {
+							if rule, err = utilities.ParseRule(tomlConf.DriveWatch.Rule); err != nil {
+								return err
+							}
+						}
+						notifier, err := utilities.NewNotifier(tomlConf.DriveWatch.Notifier, tomlConf.DriveWatch.URL, os.Stdout)
+						if err != nil {
+							return err
+						}
+
+						a := action.(*actions.DriveAction)
+						if user := context.String("impersonate"); user != "" {
+							s, err := impersonateDriveService(tomlConf.ServiceAccount.KeyFile, drive.DriveReadonlyScope)(user)
+							if err != nil {
+								return err
+							}
+							a = &actions.DriveAction{DriveService: s}
+						}
+						return a.Watch(context.String("state"), tomlConf.GetAllDomains(), rule, notifier,
+							context.Duration("interval"), context.Bool("once"))
+					},
+				},
+				{
+					Name: "remediate", Usage: "revoke or downgrade exposing permissions. Original permissions are recorded in undo log",
+					Flags: []cli.Flag{
+						cli.StringFlag{Name: "input", Usage: "output of `drive exposure` (csv or json). Exposures are audited if omitted"},
+						cli.StringFlag{Name: "filter", Usage: "rule to select exposures. Example: exposure == \"external_user\" AND role == \"writer\""},
+						cli.StringFlag{Name: "action", Value: actions.RemediationDomainOnly, Usage: "domain-only, remove or downgrade"},
+						cli.StringFlag{Name: "undo-log", Value: "gsuite_drive_undo.jsonl", Usage: "file to append original permissions to"},
+						cli.BoolFlag{Name: "impersonate", Usage: "change permissions as owner of each file with service account"},
+						cli.BoolFlag{Name: "dry-run", Usage: "only show permissions to change"},
+					},
+					Action: func(context *cli.Context) error {
+						var impersonate func(email string) (*services.DriveService, error)
+						var owners []string
+						if context.Bool("impersonate") {
+							impersonate = impersonateDriveService(tomlConf.ServiceAccount.KeyFile, drive.DriveScope)
+							if owners, err = getActiveUserEmails(gsuiteClient, tomlConf.Owner.Domain); err != nil {
+								return err
+							}
+						}
+
+						var exposures []*actions.Exposure
+						if input := context.String("input"); input != "" {
+							exposures, err = actions.LoadExposures(input)
+						} else {
+							exposures, err = action.(*actions.DriveAction).FindExposures(tomlConf.GetAllDomains(), owners, impersonate)
+						}
+						if err != nil {
+							return err
+						}
+						return action.(*actions.DriveAction).RemediateExposures(exposures, context.String("filter"), context.String("action"),
+							tomlConf.Owner.Domain, context.String("undo-log"), impersonate, context.Bool("dry-run"))
+					},
+				},
+				{
+					Name: "undo", Usage: "restore permissions recorded in undo log by `drive remediate`",
+					Flags: []cli.Flag{
+						cli.StringFlag{Name: "undo-log", Value: "gsuite_drive_undo.jsonl", Usage: "undo log written by `drive remediate`"},
+						cli.BoolFlag{Name: "impersonate", Usage: "restore permissions as owner of each file with service account"},
+						cli.BoolFlag{Name: "dry-run", Usage: "only show permissions to restore"},
+					},
+					Action: func(context *cli.Context) error {
+						var impersonate func(email string) (*services.DriveService, error)
+						if context.Bool("impersonate") {
+							impersonate = impersonateDriveService(tomlConf.ServiceAccount.KeyFile, drive.DriveScope)
+						}
+						return action.(*actions.DriveAction).UndoRemediation(context.String("undo-log"), impersonate, context.Bool("dry-run"))
+					},
+				},
+				{
+					Name: "search", Usage: "search files by flags, or folders by a keyword as an argument",
+					Flags: []cli.Flag{
+						cli.StringFlag{Name: "name", Usage: "name contains this"},
+						cli.StringFlag{Name: "fulltext", Usage: "name, description or content contains this"},
+						cli.StringSliceFlag{Name: "mime-type", Usage: "mimeType is one of these. ex) application/vnd.google-apps.form"},
+						cli.StringSliceFlag{Name: "owner", Usage: "owned by one of these emails"},
+						cli.StringFlag{Name: "modified-after", Usage: "modified after this date. ex) 2017-08-01"},
+						cli.StringFlag{Name: "modified-before", Usage: "modified before this date. ex) 2017-09-01"},
+						cli.StringSliceFlag{Name: "visibility", Usage: "anyoneCanFind, anyoneWithLink, domainCanFind, domainWithLink or limited"},
+						cli.BoolFlag{Name: "trashed", Usage: "search files in trash instead"},
+						cli.BoolFlag{Name: "any", Usage: "match files satisfying any of the conditions above instead of all"},
+						cli.StringFlag{Name: "format", Value: utilities.CSV, Usage: "output format: csv or json"},
+					},
+					Action: func(context *cli.Context) error {
+						query, err := getDriveQuery(context)
+						if err != nil {
+							return err
+						} else if query == nil {
+							if context.NArg() != 1 {
+								return errors.New("Number of keyword must be exactly 1")
+							}
+							return action.(*actions.DriveAction).SearchFoldersByName(context.Args()[0])
+						}
+						return action.(*actions.DriveAction).SearchFiles(query, context.String("format"))
+					},
+				},
+				{
+					Name: "activity", Usage: "list who edited, moved, renamed or shared a file: activity <file or folder ID>. With --views, views of the item are included as well",
+					Flags: []cli.Flag{
+						cli.BoolFlag{Name: "recursive", Usage: "list activities of every item under the folder as well"},
+						cli.BoolFlag{Name: "views", Usage: "include views of the item from Drive audit log. Views of items under the folder are not included"},
+						cli.StringFlag{Name: "format", Value: utilities.CSV, Usage: "output format: csv or json"},
+					},
 					Action: func(context *cli.Context) error {
 						if context.NArg() != 1 {
-							return errors.New("Number of keyword must be exactly 1")
+							return errors.New("Specify ID of a file or folder.")
+						}
+						s := services.InitDriveActivityService()
+						if err = s.SetClient(gsuiteClient); err != nil {
+							return err
+						}
+						if err = setServiceToAction(s, action); err != nil {
+							return err
+						}
+						if context.Bool("views") {
+							audit := services.InitAuditService()
+							if err = audit.SetClient(gsuiteClient); err != nil {
+								return err
+							}
+							if err = setServiceToAction(audit, action); err != nil {
+								return err
+							}
 						}
-						return action.(*actions.DriveAction).SearchFoldersByName(context.Args()[0])
+						return action.(*actions.DriveAction).ShowActivity(context.Args()[0], context.Bool("recursive"),
+							context.Bool("views"), context.String("format"))
+					},
+				},
+			},
+		},
+		{
+			Name: "orgunit", Category: "orgunit",
+			Usage: "View and manage organization units",
+			Before: func(*cli.Context) error {
+				service = services.InitOrganizationService()
+				if err = service.SetClient(gsuiteClient); err != nil {
+					return err
+				}
+				action = actions.InitOrganizationAction()
+				if err = setServiceToAction(service, action); err != nil {
+					return err
+				}
+				s := services.InitUserService()
+				if err = s.SetClient(gsuiteClient); err != nil {
+					return err
+				}
+				return setServiceToAction(s, action)
+			},
+			Action: showHelpFunc,
+			Subcommands: []cli.Command{
+				{
+					Name: "tree", Usage: "show hierarchy of org units with number of users",
+					Action: func(context *cli.Context) error {
+						return action.(*actions.OrganizationAction).ShowTree()
+					},
+				},
+				{
+					Name: "create", Usage: "create an org unit and its missing parents: create /path/to/unit",
+					Action: func(context *cli.Context) error {
+						if context.NArg() != 1 {
+							return errors.New("Specify path of org unit.")
+						}
+						return action.(*actions.OrganizationAction).CreateOrgUnit(context.Args()[0])
+					},
+				},
+				{
+					Name: "rename", Usage: "rename an org unit: rename /path/to/unit <new name>",
+					Action: func(context *cli.Context) error {
+						if context.NArg() != 2 {
+							return errors.New("Specify path of org unit and new name.")
+						}
+						return action.(*actions.OrganizationAction).RenameOrgUnit(context.Args()[0], context.Args()[1])
+					},
+				},
+				{
+					Name: "move", Usage: "move an org unit under another one: move /path/to/unit /new/parent",
+					Action: func(context *cli.Context) error {
+						if context.NArg() != 2 {
+							return errors.New("Specify path of org unit and new parent.")
+						}
+						return action.(*actions.OrganizationAction).MoveOrgUnit(context.Args()[0], context.Args()[1])
+					},
+				},
+				{
+					Name: "plan", Usage: "show changes required to make org units match the YAML file",
+					Flags: []cli.Flag{
+						cli.StringFlag{Name: "file", Value: OrgUnitsFileName, Usage: "YAML file describing hierarchy of org units"},
+						cli.BoolFlag{Name: "delete", Usage: "plan deleting org units not in the file"},
+					},
+					Action: func(context *cli.Context) error {
+						return action.(*actions.OrganizationAction).SyncOrgUnits(context.String("file"), context.Bool("delete"), false)
+					},
+				},
+				{
+					Name: "apply", Usage: "create and update org units to match the YAML file",
+					Flags: []cli.Flag{
+						cli.StringFlag{Name: "file", Value: OrgUnitsFileName, Usage: "YAML file describing hierarchy of org units"},
+						cli.BoolFlag{Name: "delete", Usage: "delete org units not in the file"},
+					},
+					Action: func(context *cli.Context) error {
+						return action.(*actions.OrganizationAction).SyncOrgUnits(context.String("file"), context.Bool("delete"), true)
+					},
+				},
+				{
+					Name: "delete", Usage: "delete an org unit without users and child org units: delete /path/to/unit",
+					Action: func(context *cli.Context) error {
+						if context.NArg() != 1 {
+							return errors.New("Specify path of org unit.")
+						}
+						return action.(*actions.OrganizationAction).DeleteOrgUnit(context.Args()[0])
 					},
 				},
 			},
@@ -199,11 +849,11 @@ func main() {
 				action = actions.InitLoginAction()
 				service = services.InitAuditService()
 				if err = service.SetClient(gsuiteClient); err != nil {
-					return nil
+					return err
 				}
 				s1 := services.InitUserService()
 				if err = s1.SetClient(gsuiteClient); err != nil {
-					return nil
+					return err
 				}
 
 				if err = setServiceToAction(service, action); err != nil {
@@ -219,15 +869,133 @@ func main() {
 			Subcommands: []cli.Command{
 				{
 					Name: "list_admin",
+					Flags: orgUnitReportFlags,
+					Action: func(context *cli.Context) error {
+						return action.(*actions.LoginAction).GetAllAdminUsers(tomlConf.Owner.Domain, getOrgUnitReport(context))
+					},
+				},
+				{
+					Name: "move", Usage: "move users to other org units by CSV (email,orgUnitPath) or filter such as department=Sales",
+					Flags: []cli.Flag{
+						cli.StringFlag{Name: "csv", Usage: "CSV file listing email and org unit path of users"},
+						cli.StringFlag{Name: "filter", Usage: "rule or attribute=value selecting users to move"},
+						cli.StringFlag{Name: "to", Usage: "org unit path users matching filter move to"},
+						cli.StringFlag{Name: "rollback", Usage: "CSV file recording original org units (default: gsuite_move_rollback_<time>.csv)"},
+						cli.IntFlag{Name: "parallel", Value: 5, Usage: "number of concurrent moves"},
+						cli.BoolFlag{Name: "dry-run", Usage: "only print the summary"},
+					},
 					Action: func(context *cli.Context) error {
-						return action.(*actions.LoginAction).GetAllAdminUsers(tomlConf.Owner.Domain)
+						a := actions.InitUserAction()
+						s := services.InitUserService()
+						if err = s.SetClient(gsuiteClient); err != nil {
+							return err
+						}
+						if err = setServiceToAction(s, a); err != nil {
+							return err
+						}
+						o := services.InitOrganizationService()
+						if err = o.SetClient(gsuiteClient); err != nil {
+							return err
+						}
+						if err = setServiceToAction(o, a); err != nil {
+							return err
+						}
+
+						rollback := context.String("rollback")
+						if rollback == "" {
+							rollback = "gsuite_move_rollback_" + time.Now().Format("20060102150405") + ".csv"
+						}
+						return a.MoveOrgUnits(context.String("csv"), context.String("filter"),
+							context.String("to"), rollback, context.Int("parallel"), context.Bool("dry-run"))
+					},
+				},
+				{
+					Name: "import", Usage: "create users listed in CSV in the format of UserDataTmpl.csv",
+					Flags: []cli.Flag{
+						cli.StringFlag{Name: "csv", Value: "UserDataTmpl.csv", Usage: "CSV file listing users to create"},
+						cli.BoolFlag{Name: "dry-run", Usage: "only print users to create"},
+					},
+					Action: func(context *cli.Context) error {
+						a := actions.InitUserAction()
+						s := services.InitUserService()
+						if err = s.SetClient(gsuiteClient); err != nil {
+							return err
+						}
+						if err = setServiceToAction(s, a); err != nil {
+							return err
+						}
+						return a.ImportUsers(context.String("csv"), context.Bool("dry-run"))
 					},
 				},
 				{
 					// TODO probably account command?
 					Name:  "non2sv", Usage: "get employees who have not enabled 2sv",
+					Flags: orgUnitReportFlags,
 					Action: func(context *cli.Context) error {
-						return action.(*actions.LoginAction).GetNon2StepVerifiedUsers()
+						return action.(*actions.LoginAction).GetNon2StepVerifiedUsers(tomlConf.Owner.Domain, getOrgUnitReport(context))
+					},
+				},
+			},
+		},
+		{
+			Name: "transfer", Category: "transfer",
+			Usage: "Transfer Drive and Calendar data of departing users to other users",
+			Before: func(*cli.Context) error {
+				service = services.InitDataTransferService()
+				if err = service.SetClient(gsuiteClient); err != nil {
+					return err
+				}
+				action = actions.InitTransferAction()
+				return setServiceToAction(service, action)
+			},
+			Action: showHelpFunc,
+			Subcommands: []cli.Command{
+				{
+					Name: "start", Usage: "start a transfer: start <from email> <to email>, or start --csv <file of from,to>",
+					Flags: []cli.Flag{
+						cli.StringFlag{Name: "csv", Usage: "CSV file listing pairs of departing user and recipient"},
+						cli.StringSliceFlag{Name: "app", Usage: "drive or calendar. Both are transferred if omitted"},
+						cli.BoolFlag{Name: "wait", Usage: "poll transfers until done"},
+						cli.DurationFlag{Name: "interval", Value: 30 * time.Second, Usage: "interval of polling"},
+						cli.DurationFlag{Name: "timeout", Value: 12 * time.Hour, Usage: "with --wait, give up waiting after this duration. 0 waits forever"},
+					},
+					Action: func(context *cli.Context) error {
+						apps := context.StringSlice("app")
+						if len(apps) == 0 {
+							apps = []string{services.TransferDrive, services.TransferCalendar}
+						}
+						if csvFile := context.String("csv"); csvFile != "" {
+							return action.(*actions.TransferAction).BulkTransfer(csvFile, apps, context.Bool("wait"), context.Duration("interval"),
+								context.Duration("timeout"))
+						}
+						if context.NArg() != 2 {
+							return errors.New("Specify email of departing user and recipient, or CSV file.")
+						}
+						return action.(*actions.TransferAction).Transfer(context.Args()[0], context.Args()[1], apps,
+							context.Bool("wait"), context.Duration("interval"), context.Duration("timeout"))
+					},
+				},
+				{
+					Name: "wait", Usage: "poll a transfer until done: wait <transfer id>",
+					Flags: []cli.Flag{
+						cli.DurationFlag{Name: "interval", Value: 30 * time.Second, Usage: "interval of polling"},
+						cli.DurationFlag{Name: "timeout", Value: 12 * time.Hour, Usage: "give up waiting after this duration. 0 waits forever"},
+					},
+					Action: func(context *cli.Context) error {
+						if context.NArg() != 1 {
+							return errors.New("Specify ID of transfer.")
+						}
+						return action.(*actions.TransferAction).Wait(context.Args()[0], context.Duration("interval"), context.Duration("timeout"))
+					},
+				},
+				{
+					Name: "list", Usage: "list past transfers",
+					Flags: []cli.Flag{
+						cli.StringFlag{Name: "status", Usage: "new, inProgress, completed or failed. All transfers if omitted"},
+						cli.StringFlag{Name: "format", Value: utilities.CSV, Usage: "output format: csv or json"},
+					},
+					Action: func(context *cli.Context) error {
+						return action.(*actions.TransferAction).ListTransfers(context.String("status"), context.String("format"))
 					},
 				},
 			},
@@ -236,5 +1004,96 @@ func main() {
 
 	sort.Sort(cli.FlagsByName(app.Flags))
 	sort.Sort(cli.CommandsByName(app.Commands))
-	app.Run(os.Args)
-}
\ No newline at end of file
+	if err = app.Run(os.Args); err != nil {
+		// Exit with non-zero status so that cron or scripts can detect failures
+		log.Fatal(err)
+	}
+}
+
+// getDriveQuery builds a query from flags of `drive search`, or returns nil if none of them is given
+func getDriveQuery(c *cli.Context) (*services.DriveQuery, error) {
+	var conditions []*services.DriveQuery
+	if c.IsSet("name") {
+		conditions = append(conditions, services.NewDriveQuery().NameContains(c.String("name")))
+	}
+	if c.IsSet("fulltext") {
+		conditions = append(conditions, services.NewDriveQuery().FullTextContains(c.String("fulltext")))
+	}
+	for _, flag := range []struct {
+		name string
+		add  func(q *services.DriveQuery, v string) *services.DriveQuery
+	}{
+		{"mime-type", (*services.DriveQuery).MimeType},
+		{"owner", (*services.DriveQuery).Owner},
+		{"visibility", (*services.DriveQuery).Visibility},
+	} {
+		var values []*services.DriveQuery
+		for _, v := range c.StringSlice(flag.name) {
+			values = append(values, flag.add(services.NewDriveQuery(), v))
+		}
+		if len(values) > 0 {
+			conditions = append(conditions, services.NewDriveQuery().Or(values...))
+		}
+	}
+	if c.IsSet("modified-after") {
+		t, err := utilities.ParseDate(c.String("modified-after"))
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, services.NewDriveQuery().ModifiedAfter(t))
+	}
+	if c.IsSet("modified-before") {
+		t, err := utilities.ParseDate(c.String("modified-before"))
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, services.NewDriveQuery().ModifiedBefore(t))
+	}
+	if len(conditions) == 0 && !c.IsSet("trashed") {
+		return nil, nil
+	}
+
+	query := services.NewDriveQuery().Trashed(c.Bool("trashed"))
+	if c.Bool("any") {
+		return query.Or(conditions...), nil
+	}
+	return query.And(conditions...), nil
+}
+
+// getActiveUserEmails lists email of users who are not suspended
+func getActiveUserEmails(gsuiteClient *http.Client, domain string) ([]string, error) {
+	s := services.InitUserService()
+	if err := s.SetClient(gsuiteClient); err != nil {
+		return nil, err
+	}
+	users, err := s.GetEmployees(domain)
+	if err != nil {
+		return nil, err
+	}
+	var emails []string
+	for _, u := range users {
+		if !u.Suspended {
+			emails = append(emails, u.PrimaryEmail)
+		}
+	}
+	return emails, nil
+}
+
+// impersonateDriveService returns a function creating DriveService which acts as a given user by service account
+func impersonateDriveService(keyFile string, scopes ...string) func(email string) (*services.DriveService, error) {
+	return func(email string) (*services.DriveService, error) {
+		c, err := client.CreateConfig().
+			SetServiceAccountKeyFilename(keyFile).
+			SetScopes(scopes).
+			SetSubject(email).
+			Build()
+		if err != nil {
+			return nil, err
+		}
+		s := services.InitDriveService()
+		if err = s.SetClient(c); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+}
@@ -1,14 +1,61 @@
 package models
 
+import (
+	"errors"
+	"fmt"
+)
+
 type TomlConfig struct {
 	Owner DomainOwner
 	Scopes []string
 	Networks map[string][]Network
+	DynamicGroups []DynamicGroup `toml:"dynamic_groups"`
+	ServiceAccount ServiceAccount `toml:"service_account"`
+	DriveWatch DriveWatch `toml:"drive_watch"`
+	SensitivePatterns []SensitivePattern `toml:"sensitive_patterns"`
+	Audit Audit
+}
+
+// Audit configures time windows of audit commands.
+// Timezone such as "Asia/Tokyo" is used for dates and named periods, or local timezone if empty.
+// FiscalYearStart is the first month of fiscal year used by fiscal-year period, such as 4 for April.
+// ArchiveDir is the directory of local archive of activities synced by `gsuite audit sync`.
+type Audit struct {
+	Timezone string
+	FiscalYearStart int `toml:"fiscal_year_start"`
+	ArchiveDir string `toml:"archive_dir"`
+}
+
+// SensitivePattern is a regular expression matched against Field ("name" or "description") of files
+// by `gsuite drive scan`. Severity is from 1 (low) to 3 (high).
+type SensitivePattern struct {
+	Name string
+	Field string
+	Pattern string
+	Severity int
+}
+
+// DriveWatch configures alerts of `gsuite drive watch`.
+// Only exposures matching Rule are alerted, or every exposure if Rule is empty.
+// Notifier is either "stdout", "webhook" or "slack". URL is the endpoint of webhook or slack.
+type DriveWatch struct {
+	Rule string
+	Notifier string
+	URL string `toml:"url"`
+}
+
+// ServiceAccount is used to impersonate each user, for example to audit files in their Drive.
+// KeyFile is a JSON key of service account granted domain-wide delegation.
+type ServiceAccount struct {
+	KeyFile string `toml:"key_file"`
 }
 
 type DomainOwner struct {
 	Domain string
 	Organization string
+	Aliases []string
+	// ArchiveOwner receives files of departed users, such as by `gsuite drive orphans --transfer`
+	ArchiveOwner string `toml:"archive_owner"`
 }
 
 type Network struct {
@@ -16,6 +63,30 @@ type Network struct {
 	Ip []string
 }
 
+// DynamicGroup is a group whose members are users matching Rule.
+// MaxRemovals caps number of members removed in a single sync. It is required, since a missing cap
+// would either remove nobody without telling why or remove everybody on a broken rule.
+type DynamicGroup struct {
+	Email string
+	Rule string
+	MaxRemovals int `toml:"max_removals"`
+}
+
+// Validate checks settings which can't be left to their zero values
+func (config *TomlConfig) Validate() error {
+	for _, g := range config.DynamicGroups {
+		if g.MaxRemovals < 1 {
+			return errors.New(fmt.Sprintf("Set max_removals of dynamic group %v to a positive number", g.Email))
+		}
+	}
+	return nil
+}
+
+// GetAllDomains returns primary domain and its aliases
+func (config *TomlConfig) GetAllDomains() []string {
+	return append([]string{config.Owner.Domain}, config.Owner.Aliases...)
+}
+
 func (config *TomlConfig) GetAllIps() []string {
 	var allIp []string
 	for _, network := range config.Networks {
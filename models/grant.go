@@ -0,0 +1,47 @@
+package models
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// Grant is a group membership which expires at Expires
+type Grant struct {
+	Group     string    `json:"group"`
+	Member    string    `json:"member"`
+	Role      string    `json:"role"`
+	GrantedAt time.Time `json:"granted_at"`
+	Expires   time.Time `json:"expires"`
+}
+
+// IsExpired checks whether grant has expired at t
+func (g *Grant) IsExpired(t time.Time) bool {
+	return !t.Before(g.Expires)
+}
+
+// LoadGrants reads grants from a state file. Missing file is regarded as no grants.
+func LoadGrants(path string) ([]*Grant, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var grants []*Grant
+	if err = json.Unmarshal(b, &grants); err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+// SaveGrants writes grants to a state file
+func SaveGrants(path string, grants []*Grant) error {
+	b, err := json.MarshalIndent(grants, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0600)
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+)
+
+// OrgUnitSpec is a desired org unit written in YAML.
+// Example:
+//   - name: CISO室
+//     description: Chief Information Security Officer
+//     children:
+//       - name: セキュリティ推進グループ
+//         block_inheritance: true
+type OrgUnitSpec struct {
+	Name             string        `yaml:"name"`
+	Description      string        `yaml:"description"`
+	BlockInheritance bool          `yaml:"block_inheritance"`
+	Children         []OrgUnitSpec `yaml:"children"`
+}
+
+// LoadOrgUnitSpecs reads hierarchy of org units under root from a YAML file
+func LoadOrgUnitSpecs(path string) ([]OrgUnitSpec, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var specs []OrgUnitSpec
+	if err = yaml.UnmarshalStrict(b, &specs); err != nil {
+		return nil, err
+	}
+	return specs, nil
+}
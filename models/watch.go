@@ -0,0 +1,59 @@
+package models
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+)
+
+// WatchState is the state of `gsuite drive watch` persisted between polls.
+// PageToken is the page token of Changes API to resume from.
+// Alerted holds IDs of permissions already alerted for each file ID, so that they are not alerted twice.
+type WatchState struct {
+	PageToken string              `json:"page_token"`
+	Alerted   map[string][]string `json:"alerted"`
+}
+
+// IsAlerted checks whether a permission of a file has been alerted
+func (s *WatchState) IsAlerted(fileId, permissionId string) bool {
+	for _, id := range s.Alerted[fileId] {
+		if id == permissionId {
+			return true
+		}
+	}
+	return false
+}
+
+// SetAlerted records a permission of a file as alerted
+func (s *WatchState) SetAlerted(fileId, permissionId string) {
+	if s.Alerted == nil {
+		s.Alerted = make(map[string][]string)
+	}
+	if !s.IsAlerted(fileId, permissionId) {
+		s.Alerted[fileId] = append(s.Alerted[fileId], permissionId)
+	}
+}
+
+// LoadWatchState reads state from a file. Missing file is regarded as the first run.
+func LoadWatchState(path string) (*WatchState, error) {
+	state := &WatchState{}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// SaveWatchState writes state to a file
+func SaveWatchState(path string, state *WatchState) error {
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0600)
+}
@@ -1,8 +1,12 @@
 package services
 
 import (
+	"errors"
+	"fmt"
 	"google.golang.org/api/admin/reports/v1"
+	"io/ioutil"
 	"net/http"
+	"net/url"
 	"time"
 	"strings"
 	"google.golang.org/api/googleapi"
@@ -38,6 +42,8 @@ type AuditActivitiesService struct {
 	*admin.ChannelsService
 	*admin.CustomerUsageReportsService
 	*http.Client
+	archive  *AuditArchive
+	basePath string
 }
 
 // Initialize AuditActivitiesService
@@ -57,21 +63,150 @@ func (s *AuditActivitiesService) SetClient(client *http.Client) (error) {
 	s.ChannelsService = srv.Channels
 	s.CustomerUsageReportsService = srv.CustomerUsageReports
 	s.Client = client
+	s.basePath = srv.BasePath
 	return nil
 }
 
-// getWhatever: This is just experimental so leave as it is.
+// Applications whose activities are available in Reports API
+var AuditApplications = []string{"admin", "login", "drive", "token", "groups", "mobile", "calendar"}
+
+// ActivityQuery is a combination of parameters of Reports API.
 // Activities: https://developers.google.com/admin-sdk/reports/v1/reference/activities/list?authuser=1
 // Parameter: https://developers.google.com/admin-sdk/reports/v1/appendix/activity/login?authuser=1
-// userKey: all or specific email
-// applicationName: ex) login
-//      choose from https://developers.google.com/admin-sdk/reports/v1/reference/activities/list?authuser=1
-// eventName: ex) login_failure
+// Actor: all or specific email. Defaults to all
+// Application: ex) login
+//      choose from AuditApplications
+// Event: ex) login_failure. All events are retrieved if empty
 //      choose from https://developers.google.com/admin-sdk/reports/v1/appendix/activity/login?authuser=1#login
-// filters: ex) login_type==google_password, login_failure_type<> login_failure_unknown
+// Filters: ex) login_type==google_password,login_failure_type<>login_failure_unknown
 //      choose from https://developers.google.com/admin-sdk/reports/v1/appendix/activity/login?authuser=1#login
-func (s *AuditActivitiesService) getWhatever() {
+// Since, Until: range of time. Unbounded if zero
+type ActivityQuery struct {
+	Actor       string
+	Application string
+	Event       string
+	Filters     string
+	Since       time.Time
+	Until       time.Time
+}
+
+// SetArchive makes activities to be read from archive instead of Reports API
+func (s *AuditActivitiesService) SetArchive(archive *AuditArchive) {
+	s.archive = archive
+}
 
+// QueryActivities retrieves all activities matching query, from archive if it is set
+func (s *AuditActivitiesService) QueryActivities(query *ActivityQuery) ([]*admin.Activity, error) {
+	if err := validateApplication(query.Application); err != nil {
+		return nil, err
+	}
+	if s.archive != nil {
+		return s.archive.Query(query)
+	}
+	var activities []*admin.Activity
+	pageToken := ""
+	for {
+		r, err := s.listActivities(query, pageToken)
+		if err != nil {
+			return nil, err
+		}
+		activities = append(activities, r.Items...)
+		if pageToken = r.NextPageToken; pageToken == "" {
+			return activities, nil
+		}
+	}
+}
+
+// SyncArchive pulls activities of application newer than its high-water mark minus overlap into archive,
+// and returns the number of newly archived ones. Overlap covers activities which Reports API makes available late.
+// Everything kept by Reports API is pulled at the first sync.
+func (s *AuditActivitiesService) SyncArchive(archive *AuditArchive, application string, overlap time.Duration) (int, error) {
+	if err := validateApplication(application); err != nil {
+		return 0, err
+	}
+	query := &ActivityQuery{Application: application}
+	if highWater := archive.HighWater(application); !highWater.IsZero() {
+		query.Since = highWater.Add(-overlap)
+	}
+
+	added := 0
+	pageToken := ""
+	var newest time.Time
+	for {
+		r, err := s.listActivities(query, pageToken)
+		if err != nil {
+			return added, err
+		}
+		n, err := archive.Append(application, r.Items)
+		added += n
+		if err != nil {
+			return added, err
+		}
+		for _, a := range r.Items {
+			if t, err := activityTime(a); err == nil && t.After(newest) {
+				newest = t
+			}
+		}
+		if pageToken = r.NextPageToken; pageToken == "" {
+			return added, archive.Commit(application, newest)
+		}
+	}
+}
+
+func validateApplication(application string) error {
+	known := false
+	for _, a := range AuditApplications {
+		known = known || a == application
+	}
+	if !known {
+		return errors.New(fmt.Sprintf("Unknown application: %v. Choose from %v", application, strings.Join(AuditApplications, ", ")))
+	}
+	return nil
+}
+
+// listActivities retrieves a page of activities matching query from Reports API.
+// It requests the API directly instead of ActivitiesListCall, whose response loses which kind of value
+// each parameter has when the value is zero. See decodeActivities.
+// https://developers.google.com/admin-sdk/reports/v1/reference/activities/list
+func (s *AuditActivitiesService) listActivities(query *ActivityQuery, pageToken string) (*admin.Activities, error) {
+	actor := query.Actor
+	if actor == "" {
+		actor = "all"
+	}
+	params := url.Values{"alt": {"json"}}
+	if query.Event != "" {
+		params.Set("eventName", query.Event)
+	}
+	if query.Filters != "" {
+		params.Set("filters", query.Filters)
+	}
+	if !query.Since.IsZero() {
+		params.Set("startTime", query.Since.Format(time.RFC3339))
+	}
+	if !query.Until.IsZero() {
+		params.Set("endTime", query.Until.Format(time.RFC3339))
+	}
+	if pageToken != "" {
+		params.Set("pageToken", pageToken)
+	}
+
+	urls := googleapi.ResolveRelative(s.basePath, "activity/users/{userKey}/applications/{applicationName}") + "?" + params.Encode()
+	req, _ := http.NewRequest(http.MethodGet, urls, nil)
+	googleapi.Expand(req.URL, map[string]string{"userKey": actor, "applicationName": query.Application})
+	res, err := s.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer googleapi.CloseBody(res)
+	if err = googleapi.CheckResponse(res); err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	activities := &admin.Activities{}
+	return activities, decodeActivities(b, activities)
 }
 
 // getAllActivities: Get All Admin Activities
@@ -89,34 +224,19 @@ func (s *AuditActivitiesService) getAllActivities() {
 //	Half_Year // This is the maximum duration GSuite can pull off: https://developers.google.com/admin-sdk/reports/v1/reference/activities/list?authuser=1
 //)
 
-// GetUserCreatedEvents lists user creation events
+// GetUserCreatedEvents lists user creation events between since and until
 // Weekly, Monthly...
-func (s *AuditActivitiesService) GetUserCreatedEvents(t time.Time) ([]*admin.Activity, error) {
-	call := s.ActivitiesService.
-		List("all", "admin").
-		EventName("CREATE_USER").
-		StartTime(t.Format(time.RFC3339)) // RFC 3339 format: ex: 2010-10-28T10:26:35.000Z
-
-	return fetchAllActivities(call)
+func (s *AuditActivitiesService) GetUserCreatedEvents(since, until time.Time) ([]*admin.Activity, error) {
+	return s.QueryActivities(&ActivityQuery{Application: "admin", Event: "CREATE_USER", Since: since, Until: until})
 }
 
-// GetPrivilegeGrantedEvents lists events in which Admin priviledge is granted
-func (s *AuditActivitiesService) GetPrivilegeGrantedEvents(t time.Time) ([]*admin.Activity, error) {
-	call := s.ActivitiesService.
-		List("all", "admin").
-		EventName("GRANT_ADMIN_PRIVILEGE").
-		StartTime(t.Format(time.RFC3339)) // RFC 3339 format: ex: 2010-10-28T10:26:35.000Z
-
-	return fetchAllActivities(call)
+// GetPrivilegeGrantedEvents lists events in which Admin priviledge is granted between since and until
+func (s *AuditActivitiesService) GetPrivilegeGrantedEvents(since, until time.Time) ([]*admin.Activity, error) {
+	return s.QueryActivities(&ActivityQuery{Application: "admin", Event: "GRANT_ADMIN_PRIVILEGE", Since: since, Until: until})
 }
 
-func (s *AuditActivitiesService) GetDelegatedPrivilegeGrantedEvents(t time.Time) ([]*admin.Activity, error) {
-	call := s.ActivitiesService.
-		List("all", "admin").
-		EventName("GRANT_DELEGATED_ADMIN_PRIVILEGES").
-		StartTime(t.Format(time.RFC3339))
-
-	return fetchAllActivities(call)
+func (s *AuditActivitiesService) GetDelegatedPrivilegeGrantedEvents(since, until time.Time) ([]*admin.Activity, error) {
+	return s.QueryActivities(&ActivityQuery{Application: "admin", Event: "GRANT_DELEGATED_ADMIN_PRIVILEGES", Since: since, Until: until})
 }
 
 // GetUserUsage returns G Suite service activities across your account's Users
@@ -156,43 +276,22 @@ func (s *AuditActivitiesService) Get2StepVerifiedStatusReport() (*admin.UsageRep
 	return usageReports, err
 }
 
-// GetLoginActivities reports login activities of all Users within organization
-// daysAgo: number of past days you are interested from present time
-// EX: GetLoginActivities(30)
-func (s *AuditActivitiesService) GetLoginActivities(daysAgo int) ([]*admin.Activity, error) {
-	time30DaysAgo := time.Now().Add(-time.Duration(daysAgo) * time.Hour * 24)
-	call := s.ActivitiesService.
-		List("all", "login").
-		EventName("login_success").
-		StartTime(time30DaysAgo.Format(time.RFC3339))
-
-	return fetchAllActivities(call)
+// GetLoginActivities reports login activities of all Users within organization between since and until.
+// Zero until means present time
+// EX: GetLoginActivities(time.Now().AddDate(0, 0, -30), time.Time{})
+func (s *AuditActivitiesService) GetLoginActivities(since, until time.Time) ([]*admin.Activity, error) {
+	return s.QueryActivities(&ActivityQuery{Application: "login", Event: "login_success", Since: since, Until: until})
 }
 
 // SuspiciousLogins reports successful, but suspicious login (judged by google)
 // Suspicious -> The login attempt had some unusual characteristics, for example the user logged in from an unfamiliar IP address
-func (s *AuditActivitiesService) GetSuspiciousLogIns(t time.Time) ([]*admin.Activity, error) {
-	call := s.ActivitiesService.
-		List("all", "login").
-		EventName("login_success").
-		Filters("is_suspicious==true").
-		StartTime(t.Format(time.RFC3339))
-
-	return fetchAllActivities(call)
+func (s *AuditActivitiesService) GetSuspiciousLogIns(since, until time.Time) ([]*admin.Activity, error) {
+	return s.QueryActivities(&ActivityQuery{Application: "login", Event: "login_success", Filters: "is_suspicious==true",
+		Since: since, Until: until})
 }
 
-// fetchAllActivities fetches all activities until NextPageToken gets empty.
-func fetchAllActivities(call *admin.ActivitiesListCall) ([]*admin.Activity, error) {
-	var activities []*admin.Activity
-	for {
-		if g, e := call.Do(); e != nil {
-			return nil, e
-		} else {
-			activities = append(activities, g.Items...)
-			if g.NextPageToken == "" {
-				return activities, nil
-			}
-			call.PageToken(g.NextPageToken)
-		}
-	}
-}
\ No newline at end of file
+// GetGroupActivities reports activities on Google Groups such as posting messages or changing settings
+// https://developers.google.com/admin-sdk/reports/v1/appendix/activity/groups
+func (s *AuditActivitiesService) GetGroupActivities(t time.Time) ([]*admin.Activity, error) {
+	return s.QueryActivities(&ActivityQuery{Application: "groups", Since: t})
+}
@@ -0,0 +1,406 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"google.golang.org/api/admin/reports/v1"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+	AuditArchive keeps activities of Reports API locally, since Reports API only keeps them for about six months.
+	Activities are stored as JSON Lines segments per application and month of their time in UTC:
+		<dir>/<application>/2017-08.jsonl
+	<dir>/state.json holds the high-water mark of each application, which is the time of the newest archived activity,
+	so that syncs resume from it. Activities are deduplicated on their IDs, so syncing an overlapping range is harmless.
+*/
+type AuditArchive struct {
+	dir   string
+	state *ArchiveState
+	keys  map[string]map[string]bool
+}
+
+// ArchiveState is persisted in state.json of AuditArchive
+type ArchiveState struct {
+	HighWater map[string]string `json:"high_water"`
+	SyncedAt  map[string]string `json:"synced_at"`
+}
+
+const archiveSegmentLayout = "2006-01"
+
+// OpenAuditArchive opens an archive in dir, creating dir if missing
+func OpenAuditArchive(dir string) (*AuditArchive, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	a := &AuditArchive{dir, &ArchiveState{make(map[string]string), make(map[string]string)}, make(map[string]map[string]bool)}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "state.json"))
+	if os.IsNotExist(err) {
+		return a, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, a.state); err != nil {
+		return nil, errors.New(fmt.Sprintf("Broken state of archive %v: %v", dir, err))
+	}
+	return a, nil
+}
+
+// HighWater returns time of the newest archived activity of application, or zero if none is archived
+func (a *AuditArchive) HighWater(application string) time.Time {
+	t, _ := time.Parse(time.RFC3339, a.state.HighWater[application])
+	return t
+}
+
+// Append adds activities of application not archived yet, and returns the number of them.
+// High-water mark is not moved until Commit, so that an interrupted sync is resumed from the previous mark.
+func (a *AuditArchive) Append(application string, activities []*admin.Activity) (int, error) {
+	segments := make(map[string][]*admin.Activity)
+	for _, activity := range activities {
+		t, err := activityTime(activity)
+		if err != nil {
+			return 0, err
+		}
+		segment := t.UTC().Format(archiveSegmentLayout)
+		segments[segment] = append(segments[segment], activity)
+	}
+
+	added := 0
+	for segment, activities := range segments {
+		path := filepath.Join(a.dir, application, segment+".jsonl")
+		keys, err := a.segmentKeys(path)
+		if err != nil {
+			return added, err
+		}
+		if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return added, err
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return added, err
+		}
+		encoder := json.NewEncoder(f)
+		for _, activity := range activities {
+			key := activityKey(activity)
+			if keys[key] {
+				continue
+			}
+			if err = encoder.Encode(activity); err != nil {
+				f.Close()
+				return added, err
+			}
+			keys[key] = true
+			added++
+		}
+		if err = f.Close(); err != nil {
+			return added, err
+		}
+	}
+	return added, nil
+}
+
+// Commit moves high-water mark of application to the newest archived activity, and saves state
+func (a *AuditArchive) Commit(application string, newest time.Time) error {
+	if newest.After(a.HighWater(application)) {
+		a.state.HighWater[application] = newest.UTC().Format(time.RFC3339)
+	}
+	a.state.SyncedAt[application] = time.Now().UTC().Format(time.RFC3339)
+	b, err := json.MarshalIndent(a.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(a.dir, "state.json"), b, 0600)
+}
+
+// Query returns archived activities matching query from the newest, as Reports API does.
+// Filters support ==, <>, <, <=, > and >= joined by commas, which all must be satisfied.
+// As Reports API, only events matching Event and Filters remain in each activity.
+// If the query reaches later than the high-water mark of the application, a warning is written to stderr,
+// since activities after the last sync are missing from the result.
+func (a *AuditArchive) Query(query *ActivityQuery) ([]*admin.Activity, error) {
+	filters, err := parseActivityFilters(query.Filters)
+	if err != nil {
+		return nil, err
+	}
+	a.warnUnsynced(query)
+	paths, err := filepath.Glob(filepath.Join(a.dir, query.Application, "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+
+	var activities []*admin.Activity
+	for _, path := range paths {
+		month, err := time.Parse(archiveSegmentLayout, strings.TrimSuffix(filepath.Base(path), ".jsonl"))
+		if err != nil {
+			continue
+		}
+		if !query.Since.IsZero() && !month.AddDate(0, 1, 0).After(query.Since) ||
+			!query.Until.IsZero() && !month.Before(query.Until) {
+			continue
+		}
+		err = readSegment(path, func(activity *admin.Activity) error {
+			if matched, err := matchActivity(activity, query, filters); err != nil {
+				return err
+			} else if matched != nil {
+				activities = append(activities, matched)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(activities, func(i, j int) bool {
+		ti, _ := activityTime(activities[i])
+		tj, _ := activityTime(activities[j])
+		return ti.After(tj)
+	})
+	return activities, nil
+}
+
+// warnUnsynced warns that the query reaches later than archived activities of the application
+func (a *AuditArchive) warnUnsynced(query *ActivityQuery) {
+	until := query.Until
+	if until.IsZero() {
+		until = time.Now()
+	}
+	highWater := a.HighWater(query.Application)
+	if highWater.IsZero() {
+		fmt.Fprintf(os.Stderr, "Warning: no activities of %v are archived. Run `audit sync` first.\n", query.Application)
+	} else if until.After(highWater) {
+		fmt.Fprintf(os.Stderr, "Warning: activities of %v are archived only until %v. Later ones are missing until `audit sync` is run.\n",
+			query.Application, highWater.Format(time.RFC3339))
+	}
+}
+
+// segmentKeys loads keys of activities in a segment at the first call
+func (a *AuditArchive) segmentKeys(path string) (map[string]bool, error) {
+	if keys, ok := a.keys[path]; ok {
+		return keys, nil
+	}
+	keys := make(map[string]bool)
+	err := readSegment(path, func(activity *admin.Activity) error {
+		keys[activityKey(activity)] = true
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	a.keys[path] = keys
+	return keys, nil
+}
+
+func readSegment(path string, fn func(*admin.Activity) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		activity := &admin.Activity{}
+		if err = decodeActivities(scanner.Bytes(), activity); err != nil {
+			return errors.New(fmt.Sprintf("Broken activity at %v:%d: %v", path, line, err))
+		}
+		if err = fn(activity); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// activityKey identifies an activity. Unique qualifier is unique only among activities of the same time.
+func activityKey(activity *admin.Activity) string {
+	if activity.Id == nil {
+		return ""
+	}
+	return activity.Id.Time + "/" + strconv.FormatInt(activity.Id.UniqueQualifier, 10)
+}
+
+func activityTime(activity *admin.Activity) (time.Time, error) {
+	if activity.Id == nil {
+		return time.Time{}, errors.New("Activity without ID")
+	}
+	return time.Parse(time.RFC3339, activity.Id.Time)
+}
+
+type activityFilter struct {
+	name, operator, value string
+}
+
+// parseActivityFilters parses filters of Reports API such as "login_type==google_password,is_suspicious==true"
+func parseActivityFilters(filters string) ([]activityFilter, error) {
+	var parsed []activityFilter
+	if filters == "" {
+		return parsed, nil
+	}
+	for _, f := range strings.Split(filters, ",") {
+		found := false
+		for _, op := range []string{"==", "<>", "<=", ">=", "<", ">"} {
+			if i := strings.Index(f, op); i >= 0 {
+				parsed = append(parsed, activityFilter{strings.TrimSpace(f[:i]), op, strings.TrimSpace(f[i+len(op):])})
+				found = parsed[len(parsed)-1].name != ""
+				break
+			}
+		}
+		if !found {
+			return nil, errors.New(fmt.Sprintf("Invalid filter: %v. Use ==, <>, <, <=, > or >=", f))
+		}
+	}
+	return parsed, nil
+}
+
+// matchActivity returns a copy of activity holding only events matching query, or nil if none matches
+func matchActivity(activity *admin.Activity, query *ActivityQuery, filters []activityFilter) (*admin.Activity, error) {
+	t, err := activityTime(activity)
+	if err != nil {
+		return nil, err
+	}
+	if !query.Since.IsZero() && t.Before(query.Since) || !query.Until.IsZero() && !t.Before(query.Until) {
+		return nil, nil
+	}
+	if query.Actor != "" && query.Actor != "all" && (activity.Actor == nil ||
+		!strings.EqualFold(activity.Actor.Email, query.Actor) && activity.Actor.ProfileId != query.Actor) {
+		return nil, nil
+	}
+
+	var events []*admin.ActivityEvents
+	for _, e := range activity.Events {
+		if query.Event != "" && e.Name != query.Event {
+			continue
+		}
+		if matchEventFilters(e, filters) {
+			events = append(events, e)
+		}
+	}
+	if len(events) == 0 {
+		return nil, nil
+	}
+	matched := *activity
+	matched.Events = events
+	return &matched, nil
+}
+
+func matchEventFilters(e *admin.ActivityEvents, filters []activityFilter) bool {
+	for _, f := range filters {
+		value, found := "", false
+		for _, p := range e.Parameters {
+			if p.Name == f.name {
+				value, found = ParameterValue(p), true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+		switch f.operator {
+		case "==":
+			if value != f.value {
+				return false
+			}
+		case "<>":
+			if value == f.value {
+				return false
+			}
+		default:
+			v, err1 := strconv.ParseInt(value, 10, 64)
+			w, err2 := strconv.ParseInt(f.value, 10, 64)
+			if err1 != nil || err2 != nil ||
+				f.operator == "<" && !(v < w) || f.operator == "<=" && !(v <= w) ||
+				f.operator == ">" && !(v > w) || f.operator == ">=" && !(v >= w) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ParameterValue formats whichever value a parameter has. Multiple values are joined by spaces.
+// Integer and boolean values are told by the kind recorded by decodeActivities, since their zero values are omitted.
+func ParameterValue(p *admin.ActivityEventsParameters) string {
+	switch {
+	case p.Value != "":
+		return p.Value
+	case len(p.MultiValue) > 0:
+		return strings.Join(p.MultiValue, " ")
+	case len(p.MultiIntValue) > 0:
+		values := make([]string, len(p.MultiIntValue))
+		for i, v := range p.MultiIntValue {
+			values[i] = strconv.FormatInt(v, 10)
+		}
+		return strings.Join(values, " ")
+	case p.IntValue != 0 || hasField(p.ForceSendFields, "IntValue"):
+		return strconv.FormatInt(p.IntValue, 10)
+	case p.BoolValue || hasField(p.ForceSendFields, "BoolValue"):
+		return strconv.FormatBool(p.BoolValue)
+	}
+	return ""
+}
+
+// rawActivity holds parameters of an activity as they are in JSON
+type rawActivity struct {
+	Events []struct {
+		Parameters []map[string]json.RawMessage `json:"parameters"`
+	} `json:"events"`
+}
+
+// decodeActivities decodes JSON of either admin.Activity or admin.Activities into v.
+// admin.ActivityEventsParameters drops zero intValue and boolValue, so that the kind of such value is lost.
+// Kinds of them are recorded in ForceSendFields, which ParameterValue reads and MarshalJSON writes back into archive.
+func decodeActivities(b []byte, v interface{}) error {
+	if err := json.Unmarshal(b, v); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case *admin.Activity:
+		raw := rawActivity{}
+		if err := json.Unmarshal(b, &raw); err != nil {
+			return err
+		}
+		recordParameterKinds(v, &raw)
+	case *admin.Activities:
+		raw := struct {
+			Items []rawActivity `json:"items"`
+		}{}
+		if err := json.Unmarshal(b, &raw); err != nil {
+			return err
+		}
+		for i := 0; i < len(v.Items) && i < len(raw.Items); i++ {
+			recordParameterKinds(v.Items[i], &raw.Items[i])
+		}
+	}
+	return nil
+}
+
+func recordParameterKinds(activity *admin.Activity, raw *rawActivity) {
+	for i := 0; i < len(activity.Events) && i < len(raw.Events); i++ {
+		parameters := activity.Events[i].Parameters
+		for j := 0; j < len(parameters) && j < len(raw.Events[i].Parameters); j++ {
+			p := parameters[j]
+			for _, kind := range [][2]string{{"intValue", "IntValue"}, {"boolValue", "BoolValue"}} {
+				if _, ok := raw.Events[i].Parameters[j][kind[0]]; ok && !hasField(p.ForceSendFields, kind[1]) {
+					p.ForceSendFields = append(p.ForceSendFields, kind[1])
+				}
+			}
+		}
+	}
+}
+
+func hasField(fields []string, field string) bool {
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"google.golang.org/api/admin/reports/v1"
+	"reflect"
+	"testing"
+)
+
+func TestParseActivityFilters(t *testing.T) {
+	cases := []struct {
+		filters string
+		want    []activityFilter
+	}{
+		{"", nil},
+		{"login_type==google_password", []activityFilter{{"login_type", "==", "google_password"}}},
+		{"login_type==google_password,is_suspicious==true", []activityFilter{
+			{"login_type", "==", "google_password"},
+			{"is_suspicious", "==", "true"},
+		}},
+		{" doc_type <> document ", []activityFilter{{"doc_type", "<>", "document"}}},
+		{"count<=3,count>=1", []activityFilter{{"count", "<=", "3"}, {"count", ">=", "1"}}},
+		{"count<3,count>1", []activityFilter{{"count", "<", "3"}, {"count", ">", "1"}}},
+		{"doc_title==", []activityFilter{{"doc_title", "==", ""}}},
+	}
+	for _, c := range cases {
+		got, err := parseActivityFilters(c.filters)
+		if err != nil {
+			t.Errorf("parseActivityFilters(%q): %v", c.filters, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("parseActivityFilters(%q) = %v, want %v", c.filters, got, c.want)
+		}
+	}
+}
+
+func TestParseActivityFiltersInvalid(t *testing.T) {
+	for _, filters := range []string{
+		"login_type",
+		"login_type=google_password",
+		"==google_password",
+		" ==google_password",
+		"login_type==google_password,",
+		"login_type==google_password,is_suspicious",
+	} {
+		if _, err := parseActivityFilters(filters); err == nil {
+			t.Errorf("parseActivityFilters(%q) succeeded", filters)
+		}
+	}
+}
+
+func TestMatchEventFilters(t *testing.T) {
+	event := &admin.ActivityEvents{
+		Name: "login_success",
+		Parameters: []*admin.ActivityEventsParameters{
+			{Name: "login_type", Value: "google_password"},
+			{Name: "is_suspicious", BoolValue: false, ForceSendFields: []string{"BoolValue"}},
+			{Name: "count", IntValue: 2},
+		},
+	}
+
+	cases := []struct {
+		filters string
+		want    bool
+	}{
+		{"", true},
+		{"login_type==google_password", true},
+		{"login_type==saml", false},
+		{"login_type<>saml", true},
+		{"login_type<>google_password", false},
+		{"is_suspicious==false", true},
+		{"is_suspicious==true", false},
+		{"login_type==google_password,is_suspicious==true", false},
+		{"count>1,count<3", true},
+		{"count>=2,count<=2", true},
+		{"count>2", false},
+		{"count<2", false},
+		{"login_type>1", false},
+		{"login_challenge_method==password", false},
+		{"login_challenge_method<>password", false},
+	}
+	for _, c := range cases {
+		filters, err := parseActivityFilters(c.filters)
+		if err != nil {
+			t.Errorf("parseActivityFilters(%q): %v", c.filters, err)
+			continue
+		}
+		if got := matchEventFilters(event, filters); got != c.want {
+			t.Errorf("matchEventFilters(%q) = %v, want %v", c.filters, got, c.want)
+		}
+	}
+}
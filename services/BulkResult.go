@@ -0,0 +1,134 @@
+package services
+
+import (
+	"fmt"
+	"github.com/ken5scal/gsuite_toolkit/utilities"
+	"google.golang.org/api/googleapi"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// BulkItemResult is outcome of a single item in a bulk operation such as creating org units
+// or adding group members. Code and Reason are taken from googleapi.Error when available.
+type BulkItemResult struct {
+	Item      string `json:"item"`
+	Succeeded bool   `json:"succeeded"`
+	Code      int    `json:"code,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	Message   string `json:"message,omitempty"`
+	Retryable bool   `json:"retryable"`
+}
+
+// BulkResult collects outcome of every item in a bulk operation.
+// It implements error interface, but use Err() to get error only when some items failed.
+type BulkResult struct {
+	Operation string            `json:"operation"`
+	Items     []*BulkItemResult `json:"items"`
+}
+
+// NewBulkResult creates a BulkResult of operation. Example: NewBulkResult("create org unit")
+func NewBulkResult(operation string) *BulkResult {
+	return &BulkResult{Operation: operation}
+}
+
+// Add records outcome of item. err should be nil when item succeeded.
+func (r *BulkResult) Add(item string, err error) {
+	result := &BulkItemResult{Item: item, Succeeded: err == nil}
+	if err != nil {
+		result.Message = err.Error()
+		result.Retryable = isRetryable(err)
+		if e, ok := err.(*googleapi.Error); ok {
+			result.Code = e.Code
+			result.Message = e.Message
+			if len(e.Errors) > 0 {
+				result.Reason = e.Errors[0].Reason
+			}
+		}
+	}
+	r.Items = append(r.Items, result)
+}
+
+// Failures returns results of items which failed
+func (r *BulkResult) Failures() []*BulkItemResult {
+	var failures []*BulkItemResult
+	for _, item := range r.Items {
+		if !item.Succeeded {
+			failures = append(failures, item)
+		}
+	}
+	return failures
+}
+
+// Err returns r as error if any item failed, otherwise nil.
+func (r *BulkResult) Err() error {
+	if len(r.Failures()) == 0 {
+		return nil
+	}
+	return r
+}
+
+// Write writes outcome of every item, succeeded or not, in format csv or json. Nothing is written if there are no items.
+func (r *BulkResult) Write(w io.Writer, format string) error {
+	if err := utilities.ValidateOutputFormat(format); err != nil {
+		return err
+	}
+	if len(r.Items) == 0 {
+		return nil
+	}
+	if format == utilities.JSON {
+		return utilities.WriteJSON(w, r)
+	}
+
+	var rows [][]string
+	for _, item := range r.Items {
+		code := ""
+		if item.Code != 0 {
+			code = strconv.Itoa(item.Code)
+		}
+		rows = append(rows, []string{r.Operation, item.Item, strconv.FormatBool(item.Succeeded), code, item.Reason,
+			strconv.FormatBool(item.Retryable), item.Message})
+	}
+	return utilities.WriteCSV(w, []string{"operation", "item", "succeeded", "code", "reason", "retryable", "message"}, rows)
+}
+
+func (r *BulkResult) Error() string {
+	failures := r.Failures()
+	message := fmt.Sprintf("Failed to %v %d of %d items:\n", r.Operation, len(failures), len(r.Items))
+	for _, f := range failures {
+		message += f.Item + " -> "
+		if f.Code != 0 {
+			message += strconv.Itoa(f.Code) + " " + f.Reason + ": "
+		}
+		message += f.Message
+		if f.Retryable {
+			message += " (retryable)"
+		}
+		message += "\n"
+	}
+	return message
+}
+
+// isRetryable judges whether the request might succeed by retrying later.
+// https://developers.google.com/admin-sdk/directory/v1/limits
+func isRetryable(err error) bool {
+	switch e := err.(type) {
+	case *googleapi.Error:
+		switch e.Code {
+		case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		for _, item := range e.Errors {
+			switch item.Reason {
+			case "rateLimitExceeded", "userRateLimitExceeded", "quotaExceeded", "backendError":
+				return true
+			}
+		}
+		return false
+	case net.Error:
+		return e.Timeout()
+	}
+	return false
+}
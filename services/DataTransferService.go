@@ -0,0 +1,257 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	datatransfer "google.golang.org/api/admin/datatransfer/v1"
+	"google.golang.org/api/admin/directory/v1"
+	"net/http"
+	"time"
+)
+
+// Applications whose data can be transferred by DataTransferService
+const (
+	TransferDrive    = "drive"
+	TransferCalendar = "calendar"
+)
+
+// Overall status of data transfers
+const (
+	TransferStatusNew        = "new"
+	TransferStatusInProgress = "inProgress"
+	TransferStatusCompleted  = "completed"
+	TransferStatusFailed     = "failed"
+)
+
+// transferApplications maps applications to their names in Data Transfer API and parameters of transfer.
+// Drive transfers both private and shared files, and Calendar releases resources booked by the old owner.
+// https://developers.google.com/admin-sdk/data-transfer/v1/parameters
+var transferApplications = map[string]struct {
+	name   string
+	params []*datatransfer.ApplicationTransferParam
+}{
+	TransferDrive:    {"Drive and Docs", []*datatransfer.ApplicationTransferParam{{Key: "PRIVACY_LEVEL", Value: []string{"PRIVATE", "SHARED"}}}},
+	TransferCalendar: {"Calendar", []*datatransfer.ApplicationTransferParam{{Key: "RELEASE_RESOURCES", Value: []string{"TRUE"}}}},
+}
+
+// DataTransferService transfers ownership of data, such as Drive files of a departing user, to another user.
+// Users are specified by email, which are resolved to user IDs required by the API.
+// Details are available in a following link.
+// https://developers.google.com/admin-sdk/data-transfer/v1/reference
+type DataTransferService struct {
+	*datatransfer.TransfersService
+	*datatransfer.ApplicationsService
+	*http.Client
+	users        *admin.UsersService
+	applications map[string]int64
+}
+
+// InitDataTransferService initializes DataTransferService
+func InitDataTransferService() *DataTransferService {
+	return &DataTransferService{}
+}
+
+// SetClient sets a client
+func (s *DataTransferService) SetClient(client *http.Client) error {
+	srv, err := datatransfer.New(client)
+	if err != nil {
+		return err
+	}
+	directory, err := admin.New(client)
+	if err != nil {
+		return err
+	}
+	s.TransfersService = srv.Transfers
+	s.ApplicationsService = srv.Applications
+	s.users = directory.Users
+	s.Client = client
+	return nil
+}
+
+// StartTransfer requests transfer of data of applications, such as TransferDrive, from a user to another
+// https://developers.google.com/admin-sdk/data-transfer/v1/reference/transfers/insert
+func (s *DataTransferService) StartTransfer(from, to string, applications []string) (*datatransfer.DataTransfer, error) {
+	if len(applications) == 0 {
+		return nil, errors.New("No applications are specified")
+	}
+
+	transfer := &datatransfer.DataTransfer{}
+	for _, a := range applications {
+		id, err := s.getApplicationId(a)
+		if err != nil {
+			return nil, err
+		}
+		transfer.ApplicationDataTransfers = append(transfer.ApplicationDataTransfers, &datatransfer.ApplicationDataTransfer{
+			ApplicationId:             id,
+			ApplicationTransferParams: transferApplications[a].params,
+		})
+	}
+
+	var err error
+	if transfer.OldOwnerUserId, err = s.getUserId(from); err != nil {
+		return nil, err
+	}
+	if transfer.NewOwnerUserId, err = s.getUserId(to); err != nil {
+		return nil, err
+	}
+	return s.TransfersService.Insert(transfer).Do()
+}
+
+// GetTransfer retrieves a transfer
+func (s *DataTransferService) GetTransfer(id string) (*datatransfer.DataTransfer, error) {
+	return s.TransfersService.Get(id).Do()
+}
+
+// WaitTransfer polls a transfer every interval until it completes or fails, or timeout passes. See WaitTransfers.
+func (s *DataTransferService) WaitTransfer(id string, interval, timeout time.Duration) (*datatransfer.DataTransfer, error) {
+	transfers, errs := s.WaitTransfers([]string{id}, interval, timeout)
+	return transfers[id], errs[id]
+}
+
+// WaitTransfers polls all pending transfers every interval until each of them completes or fails,
+// or timeout passes. Zero timeout waits forever. Retryable errors of polling, such as rate limit, are retried at the next interval.
+// It returns the last retrieved state of each transfer, and errors of transfers which failed, timed out or couldn't be retrieved.
+func (s *DataTransferService) WaitTransfers(ids []string, interval, timeout time.Duration) (map[string]*datatransfer.DataTransfer, map[string]error) {
+	transfers := make(map[string]*datatransfer.DataTransfer)
+	errs := make(map[string]error)
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	pending := ids
+	for {
+		var next []string
+		for _, id := range pending {
+			transfer, err := s.GetTransfer(id)
+			if err != nil {
+				if !isRetryable(err) {
+					errs[id] = err
+					continue
+				}
+				next = append(next, id)
+				continue
+			}
+			transfers[id] = transfer
+			switch transfer.OverallTransferStatusCode {
+			case TransferStatusCompleted:
+			case TransferStatusFailed:
+				errs[id] = errors.New(fmt.Sprintf("Transfer %v failed", id))
+			default:
+				next = append(next, id)
+			}
+		}
+
+		if pending = next; len(pending) == 0 {
+			return transfers, errs
+		}
+		if !deadline.IsZero() && time.Now().Add(interval).After(deadline) {
+			for _, id := range pending {
+				status := "unknown"
+				if t, ok := transfers[id]; ok {
+					status = t.OverallTransferStatusCode
+				}
+				errs[id] = errors.New(fmt.Sprintf("Timed out waiting transfer %v (status: %v)", id, status))
+			}
+			return transfers, errs
+		}
+		time.Sleep(interval)
+	}
+}
+
+// GetTransfers retrieves all transfers of the customer. All statuses are retrieved if status is empty.
+// https://developers.google.com/admin-sdk/data-transfer/v1/reference/transfers/list
+func (s *DataTransferService) GetTransfers(status string) ([]*datatransfer.DataTransfer, error) {
+	call := s.TransfersService.List().CustomerId("my_customer")
+	if status != "" {
+		call.Status(status)
+	}
+
+	var transfers []*datatransfer.DataTransfer
+	for {
+		r, err := call.Do()
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, r.DataTransfers...)
+		if r.NextPageToken == "" {
+			return transfers, nil
+		}
+		call.PageToken(r.NextPageToken)
+	}
+}
+
+// GetApplicationName returns application such as TransferDrive of an application ID, or the ID itself if unknown
+func (s *DataTransferService) GetApplicationName(id int64) string {
+	if err := s.loadApplications(); err == nil {
+		for a, i := range s.applications {
+			if i == id {
+				return a
+			}
+		}
+	}
+	return fmt.Sprint(id)
+}
+
+// GetUserEmail resolves email of a user ID. Deleted users are returned as the ID itself.
+func (s *DataTransferService) GetUserEmail(id string) (string, error) {
+	user, err := s.users.Get(id).Fields("primaryEmail").Do()
+	if IsNotFound(err) {
+		return id, nil
+	} else if err != nil {
+		return "", err
+	}
+	return user.PrimaryEmail, nil
+}
+
+func (s *DataTransferService) getUserId(email string) (string, error) {
+	user, err := s.users.Get(email).Fields("id").Do()
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("Failed retrieving %v: %v", email, err))
+	}
+	return user.Id, nil
+}
+
+// getApplicationId finds ID of an application by its name
+func (s *DataTransferService) getApplicationId(application string) (int64, error) {
+	if _, ok := transferApplications[application]; !ok {
+		return 0, errors.New(fmt.Sprintf("Unknown application: %v. Choose from %v or %v", application, TransferDrive, TransferCalendar))
+	}
+	if err := s.loadApplications(); err != nil {
+		return 0, err
+	}
+	id, ok := s.applications[application]
+	if !ok {
+		return 0, errors.New(fmt.Sprintf("Application %v is not available for transfer", transferApplications[application].name))
+	}
+	return id, nil
+}
+
+// loadApplications caches IDs of applications at the first call
+func (s *DataTransferService) loadApplications() error {
+	if s.applications != nil {
+		return nil
+	}
+
+	call := s.ApplicationsService.List().CustomerId("my_customer")
+	applications := make(map[string]int64)
+	for {
+		r, err := call.Do()
+		if err != nil {
+			return err
+		}
+		for _, app := range r.Applications {
+			for a, t := range transferApplications {
+				if app.Name == t.name {
+					applications[a] = app.Id
+				}
+			}
+		}
+		if r.NextPageToken == "" {
+			break
+		}
+		call.PageToken(r.NextPageToken)
+	}
+	s.applications = applications
+	return nil
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"google.golang.org/api/appsactivity/v1"
+	"net/http"
+)
+
+// DriveActivityService provides history of Drive items, such as edits, moves, renames and permission changes.
+// Note that the Apps Activity API doesn't report views. Views are available as "view" events of Drive audit in Reports API.
+// Details are available in a following link.
+// https://developers.google.com/google-apps/activity/
+type DriveActivityService struct {
+	*appsactivity.ActivitiesService
+	*http.Client
+}
+
+// InitDriveActivityService initializes DriveActivityService
+func InitDriveActivityService() *DriveActivityService {
+	return &DriveActivityService{}
+}
+
+// SetClient sets a client
+func (s *DriveActivityService) SetClient(client *http.Client) error {
+	srv, err := appsactivity.New(client)
+	if err != nil {
+		return err
+	}
+	s.ActivitiesService = srv.Activities
+	s.Client = client
+	return nil
+}
+
+// GetActivities retrieves all events of a Drive item seen by the authorized user, from the newest.
+// If recursive is true, id must be a folder and events of every item under it are retrieved as well.
+// Events are not grouped, so that each of them has its own actor and time.
+// https://developers.google.com/google-apps/activity/v1/reference/activities/list
+func (s *DriveActivityService) GetActivities(id string, recursive bool) ([]*appsactivity.Event, error) {
+	call := s.ActivitiesService.List().
+		Source("drive.google.com").
+		UserId("me").
+		GroupingStrategy("none").
+		PageSize(100)
+	if recursive {
+		call.DriveAncestorId(id)
+	} else {
+		call.DriveFileId(id)
+	}
+
+	var events []*appsactivity.Event
+	for {
+		r, err := call.Do()
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range r.Activities {
+			if len(a.SingleEvents) == 0 && a.CombinedEvent != nil {
+				events = append(events, a.CombinedEvent)
+			}
+			events = append(events, a.SingleEvents...)
+		}
+		if r.NextPageToken == "" {
+			return events, nil
+		}
+		call.PageToken(r.NextPageToken)
+	}
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Visibility of files which can be searched by DriveQuery.Visibility
+const (
+	VisibilityAnyoneCanFind  = "anyoneCanFind"
+	VisibilityAnyoneWithLink = "anyoneWithLink"
+	VisibilityDomainCanFind  = "domainCanFind"
+	VisibilityDomainWithLink = "domainWithLink"
+	VisibilityLimited        = "limited"
+)
+
+// DriveQuery builds q parameter of files.list, escaping values so that they can't change meaning of the query.
+// Conditions added by methods are combined by AND. Use Or to combine queries by OR, and And to nest a query.
+// https://developers.google.com/drive/v3/web/search-parameters
+//
+// Example: NewDriveQuery().NameContains("it's").Trashed(false).String() -> "name contains 'it\'s' and trashed = false"
+type DriveQuery struct {
+	terms []string
+	err   error
+}
+
+// NewDriveQuery creates an empty query, which matches every file
+func NewDriveQuery() *DriveQuery {
+	return &DriveQuery{}
+}
+
+// Or combines queries by OR, and adds them as a single condition.
+// Example: NewDriveQuery().Trashed(false).Or(NewDriveQuery().Owner(a), NewDriveQuery().Owner(b))
+func (q *DriveQuery) Or(queries ...*DriveQuery) *DriveQuery {
+	var terms []string
+	for _, o := range queries {
+		if o.err != nil && q.err == nil {
+			q.err = o.err
+		}
+		if s := o.String(); s != "" {
+			terms = append(terms, "("+s+")")
+		}
+	}
+	if len(terms) > 0 {
+		q.terms = append(q.terms, "("+strings.Join(terms, " or ")+")")
+	}
+	return q
+}
+
+// And adds conditions of queries, so that queries can be reused as a part of another
+func (q *DriveQuery) And(queries ...*DriveQuery) *DriveQuery {
+	for _, o := range queries {
+		if o.err != nil && q.err == nil {
+			q.err = o.err
+		}
+		if s := o.String(); s != "" {
+			q.terms = append(q.terms, "("+s+")")
+		}
+	}
+	return q
+}
+
+// NameContains matches files whose name contains name
+func (q *DriveQuery) NameContains(name string) *DriveQuery {
+	return q.add("name contains " + quote(name))
+}
+
+// NameEquals matches files whose name is exactly name
+func (q *DriveQuery) NameEquals(name string) *DriveQuery {
+	return q.add("name = " + quote(name))
+}
+
+// FullTextContains matches files whose name, description or content contains text
+func (q *DriveQuery) FullTextContains(text string) *DriveQuery {
+	return q.add("fullText contains " + quote(text))
+}
+
+// MimeType matches files of mimeType such as "application/vnd.google-apps.folder"
+func (q *DriveQuery) MimeType(mimeType string) *DriveQuery {
+	return q.add("mimeType = " + quote(mimeType))
+}
+
+// Owner matches files owned by email. "me" is the authorized user.
+func (q *DriveQuery) Owner(email string) *DriveQuery {
+	return q.add(quote(email) + " in owners")
+}
+
+// InParents matches files directly within a folder
+func (q *DriveQuery) InParents(folderId string) *DriveQuery {
+	return q.add(quote(folderId) + " in parents")
+}
+
+// ModifiedAfter matches files modified after t
+func (q *DriveQuery) ModifiedAfter(t time.Time) *DriveQuery {
+	return q.add("modifiedTime > " + quote(t.UTC().Format(time.RFC3339)))
+}
+
+// ModifiedBefore matches files modified before t
+func (q *DriveQuery) ModifiedBefore(t time.Time) *DriveQuery {
+	return q.add("modifiedTime < " + quote(t.UTC().Format(time.RFC3339)))
+}
+
+// Visibility matches files of visibility such as VisibilityAnyoneWithLink
+func (q *DriveQuery) Visibility(visibility string) *DriveQuery {
+	switch visibility {
+	case VisibilityAnyoneCanFind, VisibilityAnyoneWithLink, VisibilityDomainCanFind, VisibilityDomainWithLink, VisibilityLimited:
+	default:
+		if q.err == nil {
+			q.err = errors.New(fmt.Sprintf("Unknown visibility: %v. Choose from %v", visibility, strings.Join([]string{
+				VisibilityAnyoneCanFind, VisibilityAnyoneWithLink, VisibilityDomainCanFind, VisibilityDomainWithLink, VisibilityLimited}, ", ")))
+		}
+		return q
+	}
+	return q.add("visibility = " + quote(visibility))
+}
+
+// Trashed matches files in trash if trashed is true, otherwise files not in trash
+func (q *DriveQuery) Trashed(trashed bool) *DriveQuery {
+	if trashed {
+		return q.add("trashed = true")
+	}
+	return q.add("trashed = false")
+}
+
+// Build returns the query, or error if any of its values is invalid
+func (q *DriveQuery) Build() (string, error) {
+	if q.err != nil {
+		return "", q.err
+	}
+	return q.String(), nil
+}
+
+// String returns the query, ignoring invalid values
+func (q *DriveQuery) String() string {
+	return strings.Join(q.terms, " and ")
+}
+
+func (q *DriveQuery) add(term string) *DriveQuery {
+	q.terms = append(q.terms, term)
+	return q
+}
+
+// quote escapes backslash and single quote in value, and encloses it with single quotes
+func quote(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
@@ -2,8 +2,10 @@ package services
 
 import (
 	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
 	"net/http"
-	"fmt"
+	"strings"
+	"sync"
 )
 
 // DriveService provides Drive related administration tasks.
@@ -11,11 +13,20 @@ import (
 // https://developers.google.com/drive/v3/web/about-sdk
 type DriveService struct {
 	*drive.FilesService
+	*drive.PermissionsService
+	*drive.ChangesService
+	*drive.TeamdrivesService
 	*http.Client
 	Files []*drive.File
 	Call  *drive.FilesListCall
+	folders map[string]*drive.File
+	mu      sync.Mutex
 }
 
+// AuditFileFields are fields of files required to audit sharing settings.
+// Note that permissions are not populated for files in Team Drives. Use GetPermissions for them.
+const AuditFileFields = "nextPageToken, files(id, name, description, mimeType, owners, parents, modifiedTime, size, permissions, teamDriveId)"
+
 // Initialize DriveService
 func InitDriveService() (s *DriveService) {
 	return &DriveService{}
@@ -28,6 +39,9 @@ func (s *DriveService) SetClient(client *http.Client) (error) {
 		return err
 	}
 	s.FilesService = srv.Files
+	s.PermissionsService = srv.Permissions
+	s.ChangesService = srv.Changes
+	s.TeamdrivesService = srv.Teamdrives
 
 	s.Client = client
 	return nil
@@ -41,11 +55,14 @@ func (s *DriveService) GetDriveMaterialsWithTitle(title, mimeType string) ([]*dr
 	call := s.FilesService.
 		List().
 		//Corpus("domain").
+		Corpora("user,allTeamDrives").
+		IncludeTeamDriveItems(true).
+		SupportsTeamDrives(true).
 		Fields("*").
 		OrderBy("modifiedTime").
 		// Refer formats fof Drive query from following link.
 		// https://developers.google.com/drive/v3/web/search-parameters
-		Q(fmt.Sprintf("name contains '%v' and mimeType = '%v'", title, mimeType))
+		Q(NewDriveQuery().NameContains(title).MimeType(mimeType).String())
 
 	var reports []*drive.File
 	for {
@@ -68,11 +85,14 @@ func (s *DriveService) GetDriveMaterialsWithTitle(title, mimeType string) ([]*dr
 func (s *DriveService) GetFilesWithinDir(parentsId string) ([]*drive.File, error) {
 	s.Call = s.FilesService.
 		List().
+		Corpora("user,allTeamDrives").
+		IncludeTeamDriveItems(true).
+		SupportsTeamDrives(true).
 		OrderBy("modifiedTime").
 		Fields("*").
 		// Refer formats fof Drive query from following link.
 		// https://developers.google.com/drive/v3/web/search-parameters
-		Q(fmt.Sprintf("'%v' in parents", parentsId))
+		Q(NewDriveQuery().InParents(parentsId).String())
 
 	if e := s.RepeatCallerUntilNoPageToken(); e != nil {
 		return nil, e
@@ -81,7 +101,7 @@ func (s *DriveService) GetFilesWithinDir(parentsId string) ([]*drive.File, error
 }
 
 func (s *DriveService) GetParents(parentsId string) (*drive.File, error) {
-	return s.FilesService.Get(parentsId).Fields("name").Do()
+	return s.FilesService.Get(parentsId).SupportsTeamDrives(true).Fields("name").Do()
 }
 
 func (s *DriveService) RepeatCallerUntilNoPageToken() error {
@@ -97,4 +117,202 @@ func (s *DriveService) RepeatCallerUntilNoPageToken() error {
 		}
 		s.Call.PageToken(r.NextPageToken)
 	}
-}
\ No newline at end of file
+}
+
+// GetFiles retrieves all files matching query within corpora.
+// corpora is either "user" for files accessible by the user, "user,allTeamDrives" for those including Team Drives,
+// or "domain" for files shared to the domain.
+// https://developers.google.com/drive/v3/reference/files/list
+func (s *DriveService) GetFiles(query *DriveQuery, corpora string) ([]*drive.File, error) {
+	q, err := query.Build()
+	if err != nil {
+		return nil, err
+	}
+	s.Call = s.FilesService.
+		List().
+		Corpora(corpora).
+		Fields(AuditFileFields).
+		Q(q)
+	if strings.Contains(corpora, "allTeamDrives") {
+		s.Call.IncludeTeamDriveItems(true).SupportsTeamDrives(true)
+	}
+
+	if e := s.RepeatCallerUntilNoPageToken(); e != nil {
+		return nil, e
+	}
+	return s.Files, nil
+}
+
+// GetFilePath resolves full path of a file by following its parents, such as "/マイドライブ/Project/file".
+// Folders are cached so that files in the same folder don't request them again.
+// If a parent folder is not accessible, path starts with "...".
+// It is safe to call GetFilePath concurrently.
+func (s *DriveService) GetFilePath(f *drive.File) (string, error) {
+	path := f.Name
+	parents := f.Parents
+	for len(parents) > 0 {
+		parent, ok := s.cachedFolder(parents[0])
+		if !ok {
+			var err error
+			parent, err = s.FilesService.Get(parents[0]).SupportsTeamDrives(true).Fields("id, name, parents").Do()
+			if e, ok := err.(*googleapi.Error); ok && (e.Code == http.StatusNotFound || e.Code == http.StatusForbidden) {
+				return ".../" + path, nil
+			} else if err != nil {
+				return "", err
+			}
+			s.CacheFolder(parent)
+		}
+		path = parent.Name + "/" + path
+		parents = parent.Parents
+	}
+	return "/" + path, nil
+}
+
+// CacheFolder remembers a folder already retrieved, so that GetFilePath doesn't request it again
+func (s *DriveService) CacheFolder(folder *drive.File) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.folders == nil {
+		s.folders = make(map[string]*drive.File)
+	}
+	s.folders[folder.Id] = folder
+}
+
+func (s *DriveService) cachedFolder(id string) (*drive.File, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	folder, ok := s.folders[id]
+	return folder, ok
+}
+
+// GetChildren retrieves all files and folders directly within a folder, except trashed ones.
+// Unlike GetFilesWithinDir, it can be called concurrently.
+// https://developers.google.com/drive/v3/reference/files/list
+func (s *DriveService) GetChildren(folderId string) ([]*drive.File, error) {
+	call := s.FilesService.
+		List().
+		Corpora("user,allTeamDrives").
+		IncludeTeamDriveItems(true).
+		SupportsTeamDrives(true).
+		Fields(AuditFileFields).
+		Q(NewDriveQuery().InParents(folderId).Trashed(false).String())
+
+	var files []*drive.File
+	for {
+		r, e := call.Do()
+		if e != nil {
+			return nil, e
+		}
+		files = append(files, r.Files...)
+		if r.NextPageToken == "" {
+			return files, nil
+		}
+		call.PageToken(r.NextPageToken)
+	}
+}
+
+// GetFolder retrieves a folder, such as "root" for My Drive or ID of a Team Drive for its root folder
+func (s *DriveService) GetFolder(folderId string) (*drive.File, error) {
+	return s.FilesService.Get(folderId).
+		SupportsTeamDrives(true).
+		Fields("id, name, description, mimeType, owners, parents, modifiedTime, permissions, teamDriveId").
+		Do()
+}
+
+// GetPermission retrieves a permission of a file
+// https://developers.google.com/drive/v3/reference/permissions/get
+func (s *DriveService) GetPermission(fileId, permissionId string) (*drive.Permission, error) {
+	return s.PermissionsService.Get(fileId, permissionId).SupportsTeamDrives(true).Fields("*").Do()
+}
+
+// CreatePermission shares a file without sending notification mail
+// https://developers.google.com/drive/v3/reference/permissions/create
+func (s *DriveService) CreatePermission(fileId string, permission *drive.Permission) (*drive.Permission, error) {
+	call := s.PermissionsService.Create(fileId, permission).SupportsTeamDrives(true).Fields("*")
+	if permission.Type == "user" || permission.Type == "group" {
+		call.SendNotificationEmail(false)
+	}
+	return call.Do()
+}
+
+// UpdatePermissionRole changes role of a permission such as writer to reader
+// https://developers.google.com/drive/v3/reference/permissions/update
+func (s *DriveService) UpdatePermissionRole(fileId, permissionId, role string) (*drive.Permission, error) {
+	return s.PermissionsService.Update(fileId, permissionId, &drive.Permission{Role: role}).SupportsTeamDrives(true).Fields("*").Do()
+}
+
+// DeletePermission revokes a permission of a file
+// https://developers.google.com/drive/v3/reference/permissions/delete
+func (s *DriveService) DeletePermission(fileId, permissionId string) error {
+	return s.PermissionsService.Delete(fileId, permissionId).SupportsTeamDrives(true).Do()
+}
+// GetStartPageToken retrieves the page token for listing future changes
+// https://developers.google.com/drive/v3/reference/changes/getStartPageToken
+func (s *DriveService) GetStartPageToken() (string, error) {
+	r, err := s.ChangesService.GetStartPageToken().Do()
+	if err != nil {
+		return "", err
+	}
+	return r.StartPageToken, nil
+}
+
+// GetChanges retrieves all changes since pageToken, along with the page token to resume from next time
+// https://developers.google.com/drive/v3/reference/changes/list
+func (s *DriveService) GetChanges(pageToken string) ([]*drive.Change, string, error) {
+	var changes []*drive.Change
+	for {
+		r, err := s.ChangesService.
+			List(pageToken).
+			Spaces("drive").
+			IncludeTeamDriveItems(true).
+			SupportsTeamDrives(true).
+			Fields("nextPageToken, newStartPageToken, changes(fileId, removed, file(id, name, mimeType, owners, parents, modifiedTime, size, trashed, permissions, teamDriveId))").
+			Do()
+		if err != nil {
+			return nil, "", err
+		}
+		changes = append(changes, r.Changes...)
+		if r.NextPageToken == "" {
+			return changes, r.NewStartPageToken, nil
+		}
+		pageToken = r.NextPageToken
+	}
+}
+
+// GetTeamDrives retrieves all Team Drives the authorized user is a member of.
+// Team Drives the user isn't a member of are not returned even if the user is an administrator,
+// since this version of Drive API has no way to list them with administrator's access.
+// https://developers.google.com/drive/v3/reference/teamdrives/list
+func (s *DriveService) GetTeamDrives() ([]*drive.TeamDrive, error) {
+	call := s.TeamdrivesService.List().PageSize(100)
+	var teamDrives []*drive.TeamDrive
+	for {
+		r, err := call.Do()
+		if err != nil {
+			return nil, err
+		}
+		teamDrives = append(teamDrives, r.TeamDrives...)
+		if r.NextPageToken == "" {
+			return teamDrives, nil
+		}
+		call.PageToken(r.NextPageToken)
+	}
+}
+
+// GetPermissions retrieves all permissions of a file, or members of a Team Drive if ID of a Team Drive is given
+// https://developers.google.com/drive/v3/reference/permissions/list
+func (s *DriveService) GetPermissions(fileId string) ([]*drive.Permission, error) {
+	call := s.PermissionsService.List(fileId).SupportsTeamDrives(true).Fields("*")
+	var permissions []*drive.Permission
+	for {
+		r, err := call.Do()
+		if err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, r.Permissions...)
+		if r.NextPageToken == "" {
+			return permissions, nil
+		}
+		call.PageToken(r.NextPageToken)
+	}
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"google.golang.org/api/admin/directory/v1"
+	"google.golang.org/api/groupssettings/v1"
 	"net/http"
 )
 
@@ -13,8 +14,11 @@ import (
 // https://developers.google.com/admin-sdk/directory/v1/guides/manage-groups
 type GroupService struct {
 	*admin.GroupsService
+	*admin.MembersService
+	*admin.GroupsAliasesService
 	*http.Client
 	*admin.GroupsListCall
+	SettingsService *groupssettings.GroupsService
 }
 
 // InitGroupService() creates a new instance
@@ -29,6 +33,14 @@ func (s *GroupService) SetClient(client *http.Client) (error) {
 		return err
 	}
 	s.GroupsService = srv.Groups
+	s.MembersService = srv.Members
+	s.GroupsAliasesService = srv.Groups.Aliases
+
+	settings, err := groupssettings.New(client)
+	if err != nil {
+		return err
+	}
+	s.SettingsService = settings.Groups
 	s.Client = client
 	return nil
 }
@@ -59,6 +71,74 @@ func (s *GroupService) RetrieveAllGroups(domain, email string) ([]*admin.Group,
 	}
 }
 
+// GetMembers retrieves a paginated list of all members in a group.
+// https://developers.google.com/admin-sdk/directory/v1/reference/members/list
+func (s *GroupService) GetMembers(groupKey string) ([]*admin.Member, error) {
+	call := s.MembersService.List(groupKey)
+	var members []*admin.Member
+	for {
+		m, e := call.Do()
+		if e != nil {
+			return nil, e
+		}
+		members = append(members, m.Members...)
+		if m.NextPageToken == "" {
+			return members, nil
+		}
+		call.PageToken(m.NextPageToken)
+	}
+}
+
+// AddMember adds an user or a group to a group with role such as MEMBER, MANAGER or OWNER
+// https://developers.google.com/admin-sdk/directory/v1/reference/members/insert
+func (s *GroupService) AddMember(groupKey, email, role string) (*admin.Member, error) {
+	return s.MembersService.Insert(groupKey, &admin.Member{Email: email, Role: role}).Do()
+}
+
+// RemoveMember removes a member from a group
+// https://developers.google.com/admin-sdk/directory/v1/reference/members/delete
+func (s *GroupService) RemoveMember(groupKey, email string) error {
+	return s.MembersService.Delete(groupKey, email).Do()
+}
+
+// GetAliases lists aliases of a group
+// https://developers.google.com/admin-sdk/directory/v1/reference/groups/aliases/list
+func (s *GroupService) GetAliases(groupKey string) ([]string, error) {
+	r, err := s.GroupsAliasesService.List(groupKey).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	// Each alias is returned as a map such as {"alias": "hoge@yourdomain.com", ...}
+	var aliases []string
+	for _, a := range r.Aliases {
+		if m, ok := a.(map[string]interface{}); ok {
+			if alias, ok := m["alias"].(string); ok {
+				aliases = append(aliases, alias)
+			}
+		}
+	}
+	return aliases, nil
+}
+
+// AddAlias adds an alias to a group
+// https://developers.google.com/admin-sdk/directory/v1/reference/groups/aliases/insert
+func (s *GroupService) AddAlias(groupKey, alias string) (*admin.Alias, error) {
+	return s.GroupsAliasesService.Insert(groupKey, &admin.Alias{Alias: alias}).Do()
+}
+
+// RemoveAlias removes an alias from a group
+// https://developers.google.com/admin-sdk/directory/v1/reference/groups/aliases/delete
+func (s *GroupService) RemoveAlias(groupKey, alias string) error {
+	return s.GroupsAliasesService.Delete(groupKey, alias).Do()
+}
+
+// GetSettings retrieves settings of a group such as who can post messages.
+// https://developers.google.com/admin-sdk/groups-settings/v1/reference/groups/get
+func (s *GroupService) GetSettings(groupEmail string) (*groupssettings.Groups, error) {
+	return s.SettingsService.Get(groupEmail).Do()
+}
+
 func (s * GroupService) CreateGroup(groupName string) (*admin.Group, error) {
 	group := &admin.Group{
 		Name:groupName,
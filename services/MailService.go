@@ -0,0 +1,52 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"google.golang.org/api/gmail/v1"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// MailService sends notification mails on behalf of the authorized user.
+// Details are available in a following link
+// https://developers.google.com/gmail/api/guides/sending
+type MailService struct {
+	*gmail.UsersMessagesService
+	*http.Client
+}
+
+// InitMailService creates instance of Mail related Services
+func InitMailService() *MailService {
+	return &MailService{}
+}
+
+// SetClient sets a client
+func (s *MailService) SetClient(client *http.Client) error {
+	srv, err := gmail.New(client)
+	if err != nil {
+		return err
+	}
+	s.UsersMessagesService = srv.Users.Messages
+	s.Client = client
+	return nil
+}
+
+// SendMail sends a plain text mail from the authorized user
+// Example: SendMail([]string{"owner@yourdomain.co.jp"}, "件名", "本文")
+func (s *MailService) SendMail(to []string, subject, body string) error {
+	if len(to) == 0 {
+		return errors.New("No recipients are defined")
+	}
+
+	// RFC 2822 formatted message. Subject is encoded to allow non-ASCII characters.
+	raw := "To: " + strings.Join(to, ", ") + "\r\n" +
+		"Subject: " + mime.BEncoding.Encode("UTF-8", subject) + "\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + body
+
+	message := &gmail.Message{Raw: base64.URLEncoding.EncodeToString([]byte(raw))}
+	_, err := s.UsersMessagesService.Send("me", message).Do()
+	return err
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"google.golang.org/api/admin/directory/v1"
 	"net/http"
+	"sort"
+	"strings"
 )
 
 // OrganizationService provides Organization Units related functionality
@@ -35,11 +37,7 @@ func (s *OrganizationService) SetClient(client *http.Client) (error) {
 // EX: GET https://www.googleapis.com/admin/directory/v1/customer/my_customer/orgunits/corp/sales/frontline+sales
 // Example: GetOrganizationUnit("CISO室/セキュリティ推進グループ")
 func (service *OrganizationService) GetOrganizationUnit(paths ...string) (*admin.OrgUnit, error) {
-	var completePath []string
-	for _, path := range paths {
-		completePath = append(completePath, path)
-	}
-	return service.OrgunitsService.Get("my_customer", completePath).Do()
+	return service.OrgunitsService.Get("my_customer", orgUnitPathKey(paths...)).Do()
 }
 
 // GetAllOrganizationUnits fetch all sub-organization units
@@ -59,6 +57,7 @@ func (service *OrganizationService) CreateOrganizationUnit(name, parentOrgUnitPa
 }
 
 // CreateOrganizationUnits creates multiple organization units under same parent Org Unit
+// If some of them fail, it returns created ones and *BulkResult describing each outcome as error.
 // Example: CreateOrganizationUnits("CISO室", []string{"セキュリティ推進グループ", "サービスインフラグループ", "社内インフラグループ", "情報セキュリティ管理部"})
 func (service *OrganizationService) CreateOrganizationUnits(names []string, parentOrgUnitPath string) ([]*admin.OrgUnit, error) {
 	if len(names) < 1 {
@@ -71,18 +70,17 @@ func (service *OrganizationService) CreateOrganizationUnits(names []string, pare
 	}
 
 	var createdOrgUnits []*admin.OrgUnit
-	e := &OrgUnitCreateError{}
+	result := NewBulkResult("create org unit")
 
 	for _, unitName := range names {
-		r, err := service.CreateOrganizationUnit(unitName, "/"+parentOrgUnitPath)
-		if err != nil {
-			e.ConcatenateMessage(unitName, err)
-		} else {
+		r, err := service.CreateOrganizationUnit(unitName, "/"+strings.Trim(parentOrgUnitPath, "/"))
+		result.Add(unitName, err)
+		if err == nil {
 			createdOrgUnits = append(createdOrgUnits, r)
 		}
 	}
 
-	return createdOrgUnits, e
+	return createdOrgUnits, result.Err()
 }
 
 // UpdateOrganizationUnit updates an org unit specified in the path.
@@ -92,35 +90,182 @@ func (service *OrganizationService) CreateOrganizationUnits(names []string, pare
 //}
 // Example: UpdateOrganizationUnit(r, "CISO室")
 func (service *OrganizationService) UpdateOrganizationUnit(NewOrgUnit *admin.OrgUnit, paths ...string) (*admin.OrgUnit, error) {
-	var path []string
+	return service.Patch("my_customer", orgUnitPathKey(paths...), NewOrgUnit).Do()
+}
+
+// RenameOrganizationUnit changes name of an org unit. Its path changes accordingly.
+// Example: RenameOrganizationUnit("CISO室/情報セキュリティ管理部", "情報セキュリティ推進部")
+func (service *OrganizationService) RenameOrganizationUnit(path, name string) (*admin.OrgUnit, error) {
+	return service.UpdateOrganizationUnit(&admin.OrgUnit{Name: name}, path)
+}
+
+// MoveOrganizationUnit moves an org unit under another parent org unit.
+// Example: MoveOrganizationUnit("CISO室/社内インフラグループ", "/情報システム部")
+func (service *OrganizationService) MoveOrganizationUnit(path, parentOrgUnitPath string) (*admin.OrgUnit, error) {
+	return service.UpdateOrganizationUnit(&admin.OrgUnit{ParentOrgUnitPath: "/" + strings.Trim(parentOrgUnitPath, "/")}, path)
+}
+
+// DeleteOrganizationUnit deletes an org unit.
+// Google rejects deleting org units containing users or child org units.
+// DELETE https://www.googleapis.com/admin/directory/v1/customer/my_customer/orgunits/corp/sales
+func (service *OrganizationService) DeleteOrganizationUnit(paths ...string) error {
+	return service.OrgunitsService.Delete("my_customer", orgUnitPathKey(paths...)).Do()
+}
+
+// CreateOrganizationUnitPath creates an org unit together with its missing ancestors, like `mkdir -p`.
+// It returns org units newly created, and *BulkResult describing each creation. Use its Err() to check failure.
+// Example: CreateOrganizationUnitPath("/CISO室/セキュリティ推進グループ/Red Team")
+func (service *OrganizationService) CreateOrganizationUnitPath(path string) ([]*admin.OrgUnit, *BulkResult) {
+	var created []*admin.OrgUnit
+	result := NewBulkResult("create org unit")
+	parent := "/"
+	for _, name := range strings.Split(strings.Trim(path, "/"), "/") {
+		if name == "" {
+			result.Add(path, errors.New(fmt.Sprintf("Invalid org unit path: %v", path)))
+			return created, result
+		}
+
+		current := strings.TrimSuffix(parent, "/") + "/" + name
+		if _, err := service.GetOrganizationUnit(current); err == nil {
+			parent = current
+			continue
+		} else if !IsNotFound(err) {
+			result.Add(current, err)
+			return created, result
+		}
+
+		r, err := service.CreateOrganizationUnit(name, parent)
+		result.Add(current, err)
+		if err != nil {
+			return created, result
+		}
+		created = append(created, r)
+		parent = current
+	}
+	return created, result
+}
+
+// orgUnitPathKey converts path elements into a key of Orgunits API.
+// API takes full path without leading slash as a single element.
+// Example: orgUnitPathKey("/CISO室", "セキュリティ推進グループ") -> []string{"CISO室/セキュリティ推進グループ"}
+func orgUnitPathKey(paths ...string) []string {
+	var elements []string
 	for _, p := range paths {
-		path = append(path, p)
+		if p = strings.Trim(p, "/"); p != "" {
+			elements = append(elements, p)
+		}
 	}
-	return service.Patch("my_customer", path, NewOrgUnit).Do()
+	return []string{strings.Join(elements, "/")}
 }
 
-// OrgUnitCreateError implements Error interface and used when creating org unit fails
-type OrgUnitCreateError struct {
-	messages map[string]string
+// Kinds of OrgUnitChange
+const (
+	OrgUnitCreate = "create"
+	OrgUnitUpdate = "update"
+	OrgUnitDelete = "delete"
+	OrgUnitExtra  = "extra"
+)
+
+// OrgUnitChange is a change required to make an org unit match its desired state.
+// Extra is an org unit not in desired state, which is only reported.
+type OrgUnitChange struct {
+	Kind    string
+	Path    string
+	Desired *admin.OrgUnit
+	Current *admin.OrgUnit
 }
 
-func (err *OrgUnitCreateError) Error() string {
-	errorMessage := ""
+// PlanOrganizationUnits compares desired org units with existing ones and lists changes.
+// desired must contain OrgUnitPath and ParentOrgUnitPath of each org unit.
+// Creations are ordered parent-first. Org units not in desired are reported as OrgUnitExtra,
+// or as OrgUnitDelete ordered child-first if deleteExtra is true.
+func (service *OrganizationService) PlanOrganizationUnits(desired []*admin.OrgUnit, deleteExtra bool) ([]*OrgUnitChange, error) {
+	units, err := service.GetAllOrganizationUnits()
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string]*admin.OrgUnit, len(units.OrganizationUnits))
+	for _, u := range units.OrganizationUnits {
+		current[u.OrgUnitPath] = u
+	}
+
+	desiredPaths := make(map[string]bool, len(desired))
+	for _, u := range desired {
+		desiredPaths[u.OrgUnitPath] = true
+	}
 
-	for unit, message := range err.messages {
-		errorMessage = errorMessage + unit + " -> " + message + "\n"
+	var changes []*OrgUnitChange
+	sorted := append([]*admin.OrgUnit{}, desired...)
+	sort.SliceStable(sorted, func(i, j int) bool { return depth(sorted[i].OrgUnitPath) < depth(sorted[j].OrgUnitPath) })
+	for _, u := range sorted {
+		c, ok := current[u.OrgUnitPath]
+		if !ok {
+			changes = append(changes, &OrgUnitChange{OrgUnitCreate, u.OrgUnitPath, u, nil})
+		} else if c.Description != u.Description || c.BlockInheritance != u.BlockInheritance {
+			changes = append(changes, &OrgUnitChange{OrgUnitUpdate, u.OrgUnitPath, u, c})
+		}
 	}
 
-	return fmt.Sprintf("Failed creating following orgUnit:\n%s", errorMessage)
+	var extras []*admin.OrgUnit
+	for path, u := range current {
+		if !desiredPaths[path] {
+			extras = append(extras, u)
+		}
+	}
+	sort.Slice(extras, func(i, j int) bool {
+		if di, dj := depth(extras[i].OrgUnitPath), depth(extras[j].OrgUnitPath); di != dj {
+			return di > dj
+		}
+		return extras[i].OrgUnitPath < extras[j].OrgUnitPath
+	})
+	for _, u := range extras {
+		kind := OrgUnitExtra
+		if deleteExtra {
+			kind = OrgUnitDelete
+		}
+		changes = append(changes, &OrgUnitChange{kind, u.OrgUnitPath, nil, u})
+	}
+	return changes, nil
 }
 
-// ConcatenateMessage takes organizationUnit that failed to be created.
-func (err *OrgUnitCreateError) ConcatenateMessage(failedOrgUnit string, e error) {
-	if err.messages == nil {
-		err.messages = make(map[string]string)
+// ApplyOrganizationUnits carries out changes listed by PlanOrganizationUnits in order.
+// It stops at the first failure because later changes may depend on it,
+// and returns *BulkResult describing changes carried out so far. Use its Err() to check failure.
+func (service *OrganizationService) ApplyOrganizationUnits(changes []*OrgUnitChange) *BulkResult {
+	result := NewBulkResult("apply org unit change")
+	for _, c := range changes {
+		if c.Kind == OrgUnitExtra {
+			continue
+		}
+		var err error
+		switch c.Kind {
+		case OrgUnitCreate:
+			_, err = service.Insert("my_customer", &admin.OrgUnit{
+				Name:              c.Desired.Name,
+				ParentOrgUnitPath: c.Desired.ParentOrgUnitPath,
+				Description:       c.Desired.Description,
+				BlockInheritance:  c.Desired.BlockInheritance,
+			}).Do()
+		case OrgUnitUpdate:
+			_, err = service.UpdateOrganizationUnit(&admin.OrgUnit{
+				Description:      c.Desired.Description,
+				BlockInheritance: c.Desired.BlockInheritance,
+				// Send empty description and false explicitly, otherwise they are omitted
+				ForceSendFields: []string{"Description", "BlockInheritance"},
+			}, c.Path)
+		case OrgUnitDelete:
+			err = service.DeleteOrganizationUnit(c.Path)
+		}
+		result.Add(c.Kind+" "+c.Path, err)
+		if err != nil {
+			break
+		}
 	}
+	return result
+}
 
-	err.messages[failedOrgUnit] = e.Error()
+func depth(path string) int {
+	return strings.Count(strings.TrimSuffix(path, "/"), "/")
 }
 
 func (s *OrganizationService) RepeatCallerUntilNoPageToken() error {
@@ -1,7 +1,22 @@
 package services
 
-import "net/http"
+import (
+	"google.golang.org/api/googleapi"
+	"net/http"
+)
 
 type Service interface {
 	SetClient(client *http.Client) error
-}
\ No newline at end of file
+}
+
+// IsNotFound checks whether err is returned because requested resource does not exist
+func IsNotFound(err error) bool {
+	e, ok := err.(*googleapi.Error)
+	return ok && e.Code == http.StatusNotFound
+}
+
+// IsForbidden checks whether err is returned because the authorized user has no access to requested resource
+func IsForbidden(err error) bool {
+	e, ok := err.(*googleapi.Error)
+	return ok && e.Code == http.StatusForbidden
+}
@@ -14,6 +14,7 @@ import (
 	"encoding/json"
 	"net/http/httputil"
 	"bytes"
+	"strconv"
 	"strings"
 
 )
@@ -25,7 +26,6 @@ type UserService struct {
 	*admin.UsersService
 	*admin.VerificationCodesService
 	*http.Client
-	listCall *admin.UsersListCall
 }
 
 // Initialize UserService
@@ -42,32 +42,31 @@ func (s *UserService) SetClient(client *http.Client) (error) {
 	s.VerificationCodesService = srv.VerificationCodes
 	s.UsersService = srv.Users
 	s.Client = client
-	s.listCall = s.UsersService.List().OrderBy("email")
 	return nil
 }
 
 // GetAllAdmins return all Admins
 func (s *UserService) GetAllAdmins(domain string) ([]*admin.User, error) {
-	call := s.listCall.Domain(domain).Query("isAdmin=true")
+	call := s.newListCall().Domain(domain).Query("isAdmin=true")
 	return fetchAllUsers(call)
 }
 
 // GetAllAdmins return all Admins
 func (s *UserService) GetAllDelegatedAdmins(domain string) ([]*admin.User, error) {
-	call := s.listCall.Domain(domain).Query("isDelegatedAdmin=true")
+	call := s.newListCall().Domain(domain).Query("isDelegatedAdmin=true")
 	return fetchAllUsers(call)
 }
 
 // GetSuspendedEmployees retrieves users who are suspended because one of following reason:
 // https://developers.google.com/admin-sdk/directory/v1/reference/users?authuser=1#resource
 func (s *UserService) GetSuspendedEmployees(domain string) ([]*admin.User, error) {
-	call := s.listCall.Domain(domain).Query("isSuspended=true")
+	call := s.newListCall().Domain(domain).Query("isSuspended=true")
 	return fetchAllUsers(call)
 }
 
 // GetNon2SVEmployees retrieves users who is not using 2sv for its login,
 func (s *UserService) GetNon2SVEmployees(domain string) ([]*admin.User, error) {
-	call := s.listCall.Domain(domain).Query("isEnforcedIn2Sv=false isEnrolledIn2Sv=false")
+	call := s.newListCall().Domain(domain).Query("isEnforcedIn2Sv=false isEnrolledIn2Sv=false")
 	return fetchAllUsers(call)
 }
 
@@ -75,10 +74,22 @@ func (s *UserService) GetNon2SVEmployees(domain string) ([]*admin.User, error) {
 // By Default customer key should be "my_customer"
 // max should be integer lower than 500
 func (s *UserService) GetEmployees(domain string) ([]*admin.User, error) {
-	call := s.listCall.Domain(domain)
+	call := s.newListCall().Domain(domain)
 	return fetchAllUsers(call)
 }
 
+// GetAllEmployees retrieves users of every domain of the customer, including secondary domains.
+// Users filtered by a domain miss those of the other domains.
+func (s *UserService) GetAllEmployees() ([]*admin.User, error) {
+	call := s.newListCall().Customer("my_customer")
+	return fetchAllUsers(call)
+}
+
+// newListCall creates a call listing users. A call must not be shared, since its query remains in it.
+func (s *UserService) newListCall() *admin.UsersListCall {
+	return s.UsersService.List().OrderBy("email")
+}
+
 // GetUser retrieves a user based on either email or userID
 // GET https://www.googleapis.com/admin/directory/v1/users/userKey
 // Example: GetUser("abc@abc.co.jp")
@@ -86,12 +97,34 @@ func (s *UserService) GetUser(key string) (*admin.User, error) {
 	return s.UsersService.Get(key).ViewType("domain_public").Do()
 }
 
+// CreateUser creates a user who must change password at the next login. Password is sent hashed by SHA-1.
+// organization holds properties of the user's organization such as department and title, and may be empty.
+// POST https://www.googleapis.com/admin/directory/v1/users
+func (s *UserService) CreateUser(familyName, givenName, email, password string, organization map[string]string) (*admin.User, error) {
+	user := createUserObject(familyName, givenName, email, password)
+	if len(organization) > 0 {
+		org := map[string]interface{}{"primary": true}
+		for key, value := range organization {
+			org[key] = value
+		}
+		user.Organizations = []map[string]interface{}{org}
+	}
+	return s.UsersService.Insert(user).Do()
+}
+
 // ChangeOrgUnit changes user's OrgUnit.
-// PUT https://www.googleapis.com/admin/directory/v1/users/{email/userID}
+// PATCH https://www.googleapis.com/admin/directory/v1/users/{email/userID}
 // Example: ChangeOrgUnit(user, "社員・委託社員・派遣社員・アルバイト")
 func (s *UserService) ChangeOrgUnit(user *admin.User, unit string) (*admin.User, error) {
-	user.OrgUnitPath = "/" + unit
-	return s.UsersService.Update(user.PrimaryEmail, user).Do()
+	return s.PatchOrgUnit(user.PrimaryEmail, unit)
+}
+
+// PatchOrgUnit moves a user to an OrgUnit by sending only orgUnitPath, leaving other properties untouched.
+// PATCH https://www.googleapis.com/admin/directory/v1/users/{email/userID}
+// Example: PatchOrgUnit("abc@abc.co.jp", "/CISO室/セキュリティ推進グループ")
+func (s *UserService) PatchOrgUnit(key, unit string) (*admin.User, error) {
+	user := &admin.User{OrgUnitPath: "/" + strings.Trim(unit, "/")}
+	return s.UsersService.Patch(key, user).Do()
 }
 
 // GetUsersWithRareLogin detects who has not logged in recently.
@@ -117,6 +150,42 @@ func (s *UserService) GetUsersWithRareLogin(days int, domain string) ([]*admin.U
 	return goneUsers, nil
 }
 
+// GetUserAttributes flattens user properties into attributes used by rules.
+// Organization related attributes are taken from primary (or first) organization.
+func GetUserAttributes(user *admin.User) map[string]string {
+	attributes := map[string]string{
+		"primaryEmail": user.PrimaryEmail,
+		"orgUnitPath":  user.OrgUnitPath,
+		"isAdmin":      strconv.FormatBool(user.IsAdmin),
+		"suspended":    strconv.FormatBool(user.Suspended),
+	}
+
+	// Organizations is returned as a list of maps
+	if orgs, ok := user.Organizations.([]interface{}); ok {
+		for i, o := range orgs {
+			org, ok := o.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if primary, _ := org["primary"].(bool); !primary && i > 0 {
+				continue
+			}
+			for key, name := range map[string]string{
+				"department":  "department",
+				"title":       "title",
+				"costCenter":  "costCenter",
+				"description": "employeeType",
+				"location":    "location",
+			} {
+				if v, ok := org[key].(string); ok {
+					attributes[name] = v
+				}
+			}
+		}
+	}
+	return attributes
+}
+
 // GetVerificationCodes returns verification code of user
 func (s *UserService) GetVerificationCodes(email string) ([]*admin.VerificationCode, error) {
 	vs,err := s.VerificationCodesService.List(email).Do()
@@ -1,6 +1,11 @@
 package utilities
 
 import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -29,4 +34,164 @@ func (r RequestAuditDuration) ModifyDate(t time.Time) time.Time {
 		t = t.AddDate(0, -6, -(t.Day() - 1))
 	}
 	return t
-}
\ No newline at end of file
+}
+// ParseDate parses a date such as "2017-08-01" in local time zone, or date and time in RFC3339
+func ParseDate(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// AuditRetention is how long Reports API keeps activities
+// https://support.google.com/a/answer/7061566
+const AuditRetention = 180 * 24 * time.Hour
+
+// Named periods accepted by TimeParser
+var periodNames = []string{"today", "yesterday", "this-week", "last-week", "this-month", "last-month",
+	"this-quarter", "last-quarter", "fiscal-year", "last-fiscal-year"}
+
+var relativePattern = regexp.MustCompile(`^(\d+)([wdhm])$`)
+
+// TimeWindow is a range of time from Since until Until. Zero Since or Until means unbounded.
+type TimeWindow struct {
+	Since time.Time
+	Until time.Time
+}
+
+// TimeParser parses times relative to Now in Location.
+// FiscalYearStart is the first month of fiscal year, such as time.April.
+type TimeParser struct {
+	Now             time.Time
+	Location        *time.Location
+	FiscalYearStart time.Month
+}
+
+// NewTimeParser creates TimeParser at present in timezone such as "Asia/Tokyo", or local timezone if empty.
+// fiscalYearStart defaults to January if 0.
+func NewTimeParser(timezone string, fiscalYearStart int) (*TimeParser, error) {
+	loc := time.Local
+	if timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(timezone); err != nil {
+			return nil, errors.New(fmt.Sprintf("Unknown timezone: %v", timezone))
+		}
+	}
+	if fiscalYearStart == 0 {
+		fiscalYearStart = 1
+	} else if fiscalYearStart < 1 || fiscalYearStart > 12 {
+		return nil, errors.New(fmt.Sprintf("Start month of fiscal year must be from 1 to 12: %d", fiscalYearStart))
+	}
+	return &TimeParser{time.Now().In(loc), loc, time.Month(fiscalYearStart)}, nil
+}
+
+// Parse parses either of following, and returns start and end of it.
+// Start and end are the same except for named periods and dates, whose end is the start of the next period or day.
+//   RFC3339: ex) 2017-08-01T09:00:00+09:00
+//   Date, or date and time in Location: ex) 2017-08-01, 2017-08-01T09:00:00
+//   Duration before Now in weeks, days, hours or minutes: ex) 7d, 12h, 30m. Note that m is minutes, not months
+//   Named period: today, yesterday, this-week, last-week, this-month, last-month,
+//                 this-quarter, last-quarter, fiscal-year or last-fiscal-year. Weeks start on Monday.
+func (p *TimeParser) Parse(s string) (time.Time, time.Time, error) {
+	if start, end, ok := p.period(s); ok {
+		return start, end, nil
+	}
+	if m := relativePattern.FindStringSubmatch(s); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		unit := map[string]time.Duration{"w": 7 * 24 * time.Hour, "d": 24 * time.Hour, "h": time.Hour, "m": time.Minute}[m[2]]
+		t := p.Now.Add(-time.Duration(n) * unit)
+		return t, t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.In(p.Location), t.In(p.Location), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, p.Location); err == nil {
+		return t, t.AddDate(0, 0, 1), nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02 15:04"} {
+		if t, err := time.ParseInLocation(layout, s, p.Location); err == nil {
+			return t, t, nil
+		}
+	}
+	return time.Time{}, time.Time{}, errors.New(fmt.Sprintf(
+		"Invalid time: %v. Specify RFC3339, date such as 2017-08-01, duration such as 7d or 12h, or either of %v",
+		s, strings.Join(periodNames, ", ")))
+}
+
+// ParseWindow parses since and until by Parse. Empty since or until leaves the bound zero,
+// except that until defaults to end of since if since is a named period, such as the end of last month.
+// Until of a date includes the whole day, as named periods do.
+// Ends later than Now are cut at Now.
+func (p *TimeParser) ParseWindow(since, until string) (*TimeWindow, error) {
+	w := &TimeWindow{}
+	if since != "" {
+		start, end, err := p.Parse(since)
+		if err != nil {
+			return nil, err
+		}
+		w.Since = start
+		if _, _, ok := p.period(since); ok && end.Before(p.Now) {
+			w.Until = end
+		}
+	}
+	if until != "" {
+		_, end, err := p.Parse(until)
+		if err != nil {
+			return nil, err
+		}
+		w.Until = end
+	}
+	if w.Until.After(p.Now) {
+		w.Until = time.Time{}
+	}
+	if !w.Since.IsZero() && !w.Until.IsZero() && !w.Since.Before(w.Until) {
+		return nil, errors.New(fmt.Sprintf("Since %v is not before until %v", w.Since.Format(time.RFC3339), w.Until.Format(time.RFC3339)))
+	}
+	return w, nil
+}
+
+// CheckRetention rejects windows starting earlier than AuditRetention before now, since Reports API has no activities for them
+func (w *TimeWindow) CheckRetention(now time.Time) error {
+	if !w.Since.IsZero() && now.Sub(w.Since) > AuditRetention {
+		return errors.New(fmt.Sprintf("%v is beyond %d days of retention of Reports API",
+			w.Since.Format(time.RFC3339), int(AuditRetention.Hours()/24)))
+	}
+	return nil
+}
+
+// period returns start and end of a named period. Days are those in Location even if Now is in another location.
+func (p *TimeParser) period(name string) (time.Time, time.Time, bool) {
+	y, m, d := p.Now.In(p.Location).Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, p.Location)
+	monday := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	month := time.Date(y, m, 1, 0, 0, 0, 0, p.Location)
+	quarter := time.Date(y, m-(m-1)%3, 1, 0, 0, 0, 0, p.Location)
+	fiscal := time.Date(y, p.FiscalYearStart, 1, 0, 0, 0, 0, p.Location)
+	if fiscal.After(today) {
+		fiscal = fiscal.AddDate(-1, 0, 0)
+	}
+
+	switch name {
+	case "today":
+		return today, today.AddDate(0, 0, 1), true
+	case "yesterday":
+		return today.AddDate(0, 0, -1), today, true
+	case "this-week":
+		return monday, monday.AddDate(0, 0, 7), true
+	case "last-week":
+		return monday.AddDate(0, 0, -7), monday, true
+	case "this-month":
+		return month, month.AddDate(0, 1, 0), true
+	case "last-month":
+		return month.AddDate(0, -1, 0), month, true
+	case "this-quarter":
+		return quarter, quarter.AddDate(0, 3, 0), true
+	case "last-quarter":
+		return quarter.AddDate(0, -3, 0), quarter, true
+	case "fiscal-year":
+		return fiscal, fiscal.AddDate(1, 0, 0), true
+	case "last-fiscal-year":
+		return fiscal.AddDate(-1, 0, 0), fiscal, true
+	}
+	return time.Time{}, time.Time{}, false
+}
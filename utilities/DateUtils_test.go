@@ -0,0 +1,159 @@
+package utilities
+
+import (
+	"testing"
+	"time"
+)
+
+var jst = time.FixedZone("JST", 9*60*60)
+
+func jstTime(s string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02 15:04", s, jst)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// newTestParser creates TimeParser at now in JST, whose fiscal year starts in April
+func newTestParser(now string) *TimeParser {
+	return &TimeParser{Now: jstTime(now), Location: jst, FiscalYearStart: time.April}
+}
+
+func TestTimeParserParse(t *testing.T) {
+	cases := []struct {
+		now, s     string
+		start, end string
+	}{
+		// Wednesday
+		{"2017-08-16 10:30", "today", "2017-08-16 00:00", "2017-08-17 00:00"},
+		{"2017-08-16 10:30", "yesterday", "2017-08-15 00:00", "2017-08-16 00:00"},
+		{"2017-08-16 10:30", "this-week", "2017-08-14 00:00", "2017-08-21 00:00"},
+		{"2017-08-16 10:30", "last-week", "2017-08-07 00:00", "2017-08-14 00:00"},
+		{"2017-08-16 10:30", "this-month", "2017-08-01 00:00", "2017-09-01 00:00"},
+		{"2017-08-16 10:30", "last-month", "2017-07-01 00:00", "2017-08-01 00:00"},
+		{"2017-08-16 10:30", "this-quarter", "2017-07-01 00:00", "2017-10-01 00:00"},
+		{"2017-08-16 10:30", "last-quarter", "2017-04-01 00:00", "2017-07-01 00:00"},
+		{"2017-08-16 10:30", "fiscal-year", "2017-04-01 00:00", "2018-04-01 00:00"},
+		{"2017-08-16 10:30", "last-fiscal-year", "2016-04-01 00:00", "2017-04-01 00:00"},
+		// Weeks start on Monday, even on Sunday and Monday
+		{"2017-08-20 23:59", "this-week", "2017-08-14 00:00", "2017-08-21 00:00"},
+		{"2017-08-14 00:00", "this-week", "2017-08-14 00:00", "2017-08-21 00:00"},
+		{"2017-08-14 00:00", "last-week", "2017-08-07 00:00", "2017-08-14 00:00"},
+		// Months, quarters and fiscal years across years
+		{"2018-01-05 12:00", "last-month", "2017-12-01 00:00", "2018-01-01 00:00"},
+		{"2018-01-05 12:00", "this-quarter", "2018-01-01 00:00", "2018-04-01 00:00"},
+		{"2018-01-05 12:00", "last-quarter", "2017-10-01 00:00", "2018-01-01 00:00"},
+		{"2017-12-31 23:59", "this-quarter", "2017-10-01 00:00", "2018-01-01 00:00"},
+		{"2018-03-31 23:59", "fiscal-year", "2017-04-01 00:00", "2018-04-01 00:00"},
+		{"2018-04-01 00:00", "fiscal-year", "2018-04-01 00:00", "2019-04-01 00:00"},
+		{"2018-04-01 00:00", "last-fiscal-year", "2017-04-01 00:00", "2018-04-01 00:00"},
+		// Durations before now. m is minutes
+		{"2017-08-16 10:30", "7d", "2017-08-09 10:30", "2017-08-09 10:30"},
+		{"2017-08-16 10:30", "2w", "2017-08-02 10:30", "2017-08-02 10:30"},
+		{"2017-08-16 10:30", "12h", "2017-08-15 22:30", "2017-08-15 22:30"},
+		{"2017-08-16 10:30", "30m", "2017-08-16 10:00", "2017-08-16 10:00"},
+		// Absolute times. A date lasts the whole day
+		{"2017-08-16 10:30", "2017-08-01", "2017-08-01 00:00", "2017-08-02 00:00"},
+		{"2017-08-16 10:30", "2017-08-01T09:15:00", "2017-08-01 09:15", "2017-08-01 09:15"},
+		{"2017-08-16 10:30", "2017-08-01 09:15", "2017-08-01 09:15", "2017-08-01 09:15"},
+		{"2017-08-16 10:30", "2017-08-01T00:00:00Z", "2017-08-01 09:00", "2017-08-01 09:00"},
+	}
+	for _, c := range cases {
+		start, end, err := newTestParser(c.now).Parse(c.s)
+		if err != nil {
+			t.Errorf("Parse(%q) at %v: %v", c.s, c.now, err)
+			continue
+		}
+		if !start.Equal(jstTime(c.start)) || !end.Equal(jstTime(c.end)) {
+			t.Errorf("Parse(%q) at %v = %v, %v; want %v, %v", c.s, c.now, start, end, c.start, c.end)
+		}
+	}
+}
+
+func TestTimeParserParseInvalid(t *testing.T) {
+	for _, s := range []string{"", "1mo", "7 d", "last-year", "2017-13-01", "2017/08/01"} {
+		if _, _, err := newTestParser("2017-08-16 10:30").Parse(s); err == nil {
+			t.Errorf("Parse(%q) must fail", s)
+		}
+	}
+}
+
+func TestTimeParserTimezone(t *testing.T) {
+	// 2017-08-15 16:00 in UTC is already 2017-08-16 in JST
+	p := &TimeParser{Now: time.Date(2017, 8, 15, 16, 0, 0, 0, time.UTC), Location: jst, FiscalYearStart: time.January}
+	start, end, err := p.Parse("today")
+	if err != nil || !start.Equal(jstTime("2017-08-16 00:00")) || !end.Equal(jstTime("2017-08-17 00:00")) {
+		t.Errorf("today = %v, %v, %v; want days in JST", start, end, err)
+	}
+	if start, _, _ = p.Parse("2017-08-01"); start.Location() != jst {
+		t.Errorf("dates must be in Location: %v", start)
+	}
+
+	// A day lasts 23 hours when daylight saving time starts
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("timezone database is not available")
+	}
+	p = &TimeParser{Now: time.Date(2017, 3, 20, 0, 0, 0, 0, ny), Location: ny, FiscalYearStart: time.January}
+	start, end, err = p.Parse("2017-03-12")
+	if err != nil || end.Sub(start) != 23*time.Hour {
+		t.Errorf("2017-03-12 in New York = %v, %v, %v; want 23 hours", start, end, err)
+	}
+}
+
+func TestTimeParserParseWindow(t *testing.T) {
+	cases := []struct {
+		since, until string
+		want         [2]string
+	}{
+		// Until defaults to the end of a named period, unless it is later than now
+		{"last-month", "", [2]string{"2017-07-01 00:00", "2017-08-01 00:00"}},
+		{"this-month", "", [2]string{"2017-08-01 00:00", ""}},
+		{"last-week", "2017-08-10", [2]string{"2017-08-07 00:00", "2017-08-11 00:00"}},
+		// Since of a date doesn't bound until, while until of a date includes the whole day
+		{"2017-08-01", "", [2]string{"2017-08-01 00:00", ""}},
+		{"2017-08-01", "2017-08-10", [2]string{"2017-08-01 00:00", "2017-08-11 00:00"}},
+		{"2017-08-10", "2017-08-10", [2]string{"2017-08-10 00:00", "2017-08-11 00:00"}},
+		{"", "2017-08-10", [2]string{"", "2017-08-11 00:00"}},
+		{"2017-08-01", "2017-08-10 12:00", [2]string{"2017-08-01 00:00", "2017-08-10 12:00"}},
+		// Ends later than now are cut
+		{"7d", "today", [2]string{"2017-08-09 10:30", ""}},
+		{"2017-08-16", "2017-08-16", [2]string{"2017-08-16 00:00", ""}},
+		{"", "", [2]string{"", ""}},
+	}
+	for _, c := range cases {
+		w, err := newTestParser("2017-08-16 10:30").ParseWindow(c.since, c.until)
+		if err != nil {
+			t.Errorf("ParseWindow(%q, %q): %v", c.since, c.until, err)
+			continue
+		}
+		for i, got := range []time.Time{w.Since, w.Until} {
+			if c.want[i] == "" && !got.IsZero() || c.want[i] != "" && !got.Equal(jstTime(c.want[i])) {
+				t.Errorf("ParseWindow(%q, %q) = %v, %v; want %v", c.since, c.until, w.Since, w.Until, c.want)
+				break
+			}
+		}
+	}
+}
+
+func TestTimeParserParseWindowInvalid(t *testing.T) {
+	for _, c := range [][2]string{{"2017-08-10", "2017-08-01"}, {"yesterday", "yesterday 12:00"}, {"1mo", ""}, {"", "soon"}} {
+		if _, err := newTestParser("2017-08-16 10:30").ParseWindow(c[0], c[1]); err == nil {
+			t.Errorf("ParseWindow(%q, %q) must fail", c[0], c[1])
+		}
+	}
+}
+
+func TestTimeWindowCheckRetention(t *testing.T) {
+	now := jstTime("2017-08-16 10:30")
+	if err := (&TimeWindow{Since: now.Add(-AuditRetention)}).CheckRetention(now); err != nil {
+		t.Errorf("window within retention: %v", err)
+	}
+	if err := (&TimeWindow{Since: now.Add(-AuditRetention - time.Hour)}).CheckRetention(now); err == nil {
+		t.Error("window beyond retention must fail")
+	}
+	if err := (&TimeWindow{}).CheckRetention(now); err != nil {
+		t.Errorf("unbounded window: %v", err)
+	}
+}
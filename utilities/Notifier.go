@@ -0,0 +1,131 @@
+package utilities
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Kinds of notifier
+const (
+	NotifierStdout  = "stdout"
+	NotifierWebhook = "webhook"
+	NotifierSlack   = "slack"
+)
+
+// Alert is a message sent by Notifier. Fields are details such as file ID and grantee.
+type Alert struct {
+	Title  string            `json:"title"`
+	Text   string            `json:"text"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// Notifier sends alerts to somewhere people watch, such as chat
+type Notifier interface {
+	Notify(alert *Alert) error
+}
+
+// NewNotifier creates a Notifier of kind. url is required except for stdout.
+func NewNotifier(kind, url string, stdout io.Writer) (Notifier, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	switch kind {
+	case "", NotifierStdout:
+		return &WriterNotifier{stdout}, nil
+	case NotifierWebhook, NotifierSlack:
+		if url == "" {
+			return nil, errors.New(fmt.Sprintf("URL is required for %v notifier", kind))
+		}
+		if kind == NotifierSlack {
+			return &SlackNotifier{url, client}, nil
+		}
+		return &WebhookNotifier{url, client}, nil
+	}
+	return nil, errors.New(fmt.Sprintf("Unknown notifier: %v. Choose from %v, %v or %v", kind, NotifierStdout, NotifierWebhook, NotifierSlack))
+}
+
+// WriterNotifier writes alerts as text
+type WriterNotifier struct {
+	io.Writer
+}
+
+// Notify writes alert
+func (n *WriterNotifier) Notify(alert *Alert) error {
+	text := alert.Title + "\n" + alert.Text + "\n"
+	for _, k := range sortedKeys(alert.Fields) {
+		text += "	" + k + ": " + alert.Fields[k] + "\n"
+	}
+	_, err := io.WriteString(n.Writer, text)
+	return err
+}
+
+// WebhookNotifier posts alerts as JSON to URL
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+// Notify posts alert as it is
+func (n *WebhookNotifier) Notify(alert *Alert) error {
+	return postJSON(n.Client, n.URL, alert)
+}
+
+// SlackNotifier posts alerts to Slack incoming webhook, or any endpoint compatible with it
+// https://api.slack.com/incoming-webhooks
+type SlackNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+// Notify posts alert as a message with an attachment listing fields
+func (n *SlackNotifier) Notify(alert *Alert) error {
+	type field struct {
+		Title string `json:"title"`
+		Value string `json:"value"`
+		Short bool   `json:"short"`
+	}
+	type attachment struct {
+		Color  string  `json:"color"`
+		Fields []field `json:"fields"`
+	}
+	var fields []field
+	for _, k := range sortedKeys(alert.Fields) {
+		fields = append(fields, field{k, alert.Fields[k], len(alert.Fields[k]) < 40})
+	}
+	return postJSON(n.Client, n.URL, &struct {
+		Text        string       `json:"text"`
+		Attachments []attachment `json:"attachments,omitempty"`
+	}{
+		Text:        "*" + alert.Title + "*\n" + alert.Text,
+		Attachments: []attachment{{"warning", fields}},
+	})
+}
+
+func postJSON(client *http.Client, url string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	res, err := client.Post(url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		return errors.New(fmt.Sprintf("Notification to %v failed: %v", url, res.Status))
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
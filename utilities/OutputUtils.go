@@ -0,0 +1,42 @@
+package utilities
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Supported formats of reports
+const (
+	CSV  = "csv"
+	JSON = "json"
+)
+
+// ValidateOutputFormat checks whether format is one of supported report formats.
+func ValidateOutputFormat(format string) error {
+	if format != CSV && format != JSON {
+		return errors.New(fmt.Sprintf("Unsupported format: %v. Choose from %v or %v", format, CSV, JSON))
+	}
+	return nil
+}
+
+// WriteCSV writes header and rows as CSV
+func WriteCSV(w io.Writer, header []string, rows [][]string) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+// WriteJSON writes v as indented JSON
+func WriteJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,267 @@
+package utilities
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+/*
+	Rule is a condition over string attributes such as department or orgUnitPath.
+	Syntax:
+		rule       := term { OR term }
+		term       := factor { AND factor }
+		factor     := NOT factor | "(" rule ")" | comparison
+		comparison := attribute operator "value"
+		operator   := == | != | startsWith | endsWith | contains
+	AND, OR and NOT are case insensitive. && , || and ! are also accepted.
+
+	EX) department == "Engineering" AND orgUnitPath startsWith "/Tokyo"
+*/
+
+// Rule is a parsed rule which can be evaluated against attributes
+type Rule struct {
+	source string
+	root   ruleNode
+}
+
+type ruleNode interface {
+	match(attributes map[string]string) bool
+}
+
+type andNode struct{ left, right ruleNode }
+type orNode struct{ left, right ruleNode }
+type notNode struct{ node ruleNode }
+type comparisonNode struct{ attribute, operator, value string }
+
+func (n andNode) match(a map[string]string) bool { return n.left.match(a) && n.right.match(a) }
+func (n orNode) match(a map[string]string) bool  { return n.left.match(a) || n.right.match(a) }
+func (n notNode) match(a map[string]string) bool { return !n.node.match(a) }
+
+func (n comparisonNode) match(a map[string]string) bool {
+	v := a[n.attribute]
+	switch n.operator {
+	case "==":
+		return v == n.value
+	case "!=":
+		return v != n.value
+	case "startsWith":
+		return strings.HasPrefix(v, n.value)
+	case "endsWith":
+		return strings.HasSuffix(v, n.value)
+	case "contains":
+		return strings.Contains(v, n.value)
+	}
+	return false
+}
+
+// ParseRule parses rule
+// Example: ParseRule(`department == "Engineering" AND orgUnitPath startsWith "/Tokyo"`)
+func ParseRule(source string) (*Rule, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &ruleParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, errors.New(fmt.Sprintf("Unexpected token %v in rule: %v", p.tokens[p.pos].text, source))
+	}
+	return &Rule{source, root}, nil
+}
+
+// ParseFilter parses either a rule or a shorthand filter in form of attribute=value.
+// Example: ParseFilter("department=Sales") is same as ParseRule(`department == "Sales"`)
+func ParseFilter(source string) (*Rule, error) {
+	if i := strings.Index(source, "="); i > 0 && !strings.ContainsAny(source, "\"'()!") && strings.Count(source, "=") == 1 {
+		attribute, value := strings.TrimSpace(source[:i]), strings.TrimSpace(source[i+1:])
+		if !strings.ContainsAny(attribute, " \t") {
+			return &Rule{source, comparisonNode{attribute, "==", value}}, nil
+		}
+	}
+	return ParseRule(source)
+}
+
+// Match evaluates rule against attributes. Missing attributes are regarded as empty string.
+func (r *Rule) Match(attributes map[string]string) bool {
+	return r.root.match(attributes)
+}
+
+func (r *Rule) String() string {
+	return r.source
+}
+
+type tokenKind int
+
+const (
+	identToken tokenKind = iota
+	stringToken
+	symbolToken
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			var value []rune
+			j := i + 1
+			for ; j < len(runes) && runes[j] != r; j++ {
+				if runes[j] == '\\' && j+1 < len(runes) {
+					j++
+				}
+				value = append(value, runes[j])
+			}
+			if j >= len(runes) {
+				return nil, errors.New(fmt.Sprintf("Unterminated string in rule: %v", source))
+			}
+			tokens = append(tokens, token{stringToken, string(value)})
+			i = j + 1
+		case r == '(' || r == ')':
+			tokens = append(tokens, token{symbolToken, string(r)})
+			i++
+		case strings.ContainsRune("=!&|", r):
+			if i+1 < len(runes) && isTwoCharSymbol(string(runes[i:i+2])) {
+				tokens = append(tokens, token{symbolToken, string(runes[i : i+2])})
+				i += 2
+			} else if r == '!' {
+				tokens = append(tokens, token{symbolToken, "!"})
+				i++
+			} else {
+				return nil, errors.New(fmt.Sprintf("Unexpected character %c in rule: %v", r, source))
+			}
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_' || runes[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{identToken, string(runes[i:j])})
+			i = j
+		default:
+			return nil, errors.New(fmt.Sprintf("Unexpected character %c in rule: %v", r, source))
+		}
+	}
+	return tokens, nil
+}
+
+func isTwoCharSymbol(s string) bool {
+	switch s {
+	case "==", "!=", "&&", "||":
+		return true
+	}
+	return false
+}
+
+type ruleParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *ruleParser) peek() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+// accept consumes next token if it is one of keywords
+func (p *ruleParser) accept(keywords ...string) bool {
+	t, ok := p.peek()
+	if !ok || t.kind == stringToken {
+		return false
+	}
+	for _, k := range keywords {
+		if strings.EqualFold(t.text, k) {
+			p.pos++
+			return true
+		}
+	}
+	return false
+}
+
+func (p *ruleParser) parseOr() (ruleNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("OR", "||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *ruleParser) parseAnd() (ruleNode, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("AND", "&&") {
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *ruleParser) parseFactor() (ruleNode, error) {
+	if p.accept("NOT", "!") {
+		n, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{n}, nil
+	}
+	if p.accept("(") {
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, errors.New("Missing closing parenthesis in rule")
+		}
+		return n, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *ruleParser) parseComparison() (ruleNode, error) {
+	if len(p.tokens) < p.pos+3 {
+		return nil, errors.New("Incomplete comparison in rule")
+	}
+	attribute, operator, value := p.tokens[p.pos], p.tokens[p.pos+1], p.tokens[p.pos+2]
+	if attribute.kind != identToken {
+		return nil, errors.New(fmt.Sprintf("Expected attribute name but got %v", attribute.text))
+	}
+	switch operator.text {
+	case "==", "!=", "startsWith", "endsWith", "contains":
+		if operator.kind == stringToken {
+			return nil, errors.New(fmt.Sprintf("Expected operator but got quoted %v", operator.text))
+		}
+	default:
+		return nil, errors.New(fmt.Sprintf("Unknown operator %v", operator.text))
+	}
+	if value.kind != stringToken {
+		return nil, errors.New(fmt.Sprintf("Expected quoted value but got %v", value.text))
+	}
+	p.pos += 3
+	return comparisonNode{attribute.text, operator.text, value.text}, nil
+}
@@ -0,0 +1,88 @@
+package utilities
+
+import "testing"
+
+func TestParseRule(t *testing.T) {
+	engineer := map[string]string{"department": "Engineering", "orgUnitPath": "/Tokyo/Dev", "title": "Manager", "isAdmin": "false"}
+	sales := map[string]string{"department": "Sales", "orgUnitPath": "/Osaka", "title": "Sales Manager"}
+
+	cases := []struct {
+		rule            string
+		engineer, sales bool
+	}{
+		{`department == "Engineering"`, true, false},
+		{`department != "Engineering"`, false, true},
+		{`orgUnitPath startsWith "/Tokyo"`, true, false},
+		{`title endsWith "Manager"`, true, true},
+		{`title contains "Sales"`, false, true},
+		{`location == ""`, true, true},
+		// AND binds tighter than OR
+		{`department == "Sales" OR department == "Engineering" AND orgUnitPath startsWith "/Osaka"`, false, true},
+		{`(department == "Sales" OR department == "Engineering") AND orgUnitPath startsWith "/Osaka"`, false, true},
+		{`(department == "Sales" OR department == "Engineering") AND orgUnitPath startsWith "/Tokyo"`, true, false},
+		{`NOT department == "Sales"`, true, false},
+		{`not (department == "Sales" or isAdmin == "true")`, true, false},
+		{`NOT NOT department == "Sales"`, false, true},
+		{`department == "Sales" || !(title contains "Manager") && isAdmin == "false"`, false, true},
+		{`department == "Engineering" && title == 'Manager'`, true, false},
+		{`title == "Sales \"Manager\""`, false, false},
+	}
+	for _, c := range cases {
+		rule, err := ParseRule(c.rule)
+		if err != nil {
+			t.Errorf("ParseRule(%v): %v", c.rule, err)
+			continue
+		}
+		if got := rule.Match(engineer); got != c.engineer {
+			t.Errorf("%v matched engineer: %v", c.rule, got)
+		}
+		if got := rule.Match(sales); got != c.sales {
+			t.Errorf("%v matched sales: %v", c.rule, got)
+		}
+	}
+}
+
+func TestParseRuleInvalid(t *testing.T) {
+	for _, rule := range []string{
+		``,
+		`department`,
+		`department ==`,
+		`department == Sales`,
+		`department = "Sales"`,
+		`department like "Sales"`,
+		`department "==" "Sales"`,
+		`"department" == "Sales"`,
+		`department == "Sales`,
+		`(department == "Sales"`,
+		`department == "Sales")`,
+		`department == "Sales" AND`,
+		`department == "Sales" "Engineering"`,
+	} {
+		if _, err := ParseRule(rule); err == nil {
+			t.Errorf("ParseRule(%v) must fail", rule)
+		}
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	cases := []struct {
+		filter string
+		match  bool
+	}{
+		{"department=Sales", true},
+		{" department = Sales ", true},
+		{"department=Engineering", false},
+		{`department == "Sales"`, true},
+		{`department != "Sales"`, false},
+	}
+	for _, c := range cases {
+		rule, err := ParseFilter(c.filter)
+		if err != nil {
+			t.Errorf("ParseFilter(%v): %v", c.filter, err)
+			continue
+		}
+		if got := rule.Match(map[string]string{"department": "Sales"}); got != c.match {
+			t.Errorf("%v matched: %v", c.filter, got)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package utilities
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Similarity returns how similar two strings are, from 0 (different) to 1 (same).
+// Letters are compared case-insensitively and symbols or spaces are ignored.
+// Example: Similarity("all-engineers", "All Engineer") -> 0.91...
+func Similarity(a, b string) float64 {
+	ra, rb := normalize(a), normalize(b)
+	longer := len(ra)
+	if len(rb) > longer {
+		longer = len(rb)
+	}
+	if longer == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longer)
+}
+
+func normalize(s string) []rune {
+	var runes []rune
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			runes = append(runes, r)
+		}
+	}
+	return runes
+}
+
+// levenshtein calculates edit distance between a and b
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr := make([]int, len(b)+1)
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min3(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev = curr
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// DisplayWidth returns number of columns s occupies in terminal.
+// East Asian wide characters such as Kanji or Katakana occupy 2 columns.
+func DisplayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		if isWide(r) {
+			width += 2
+		} else {
+			width++
+		}
+	}
+	return width
+}
+
+// PadRight pads s with spaces until it occupies width columns
+func PadRight(s string, width int) string {
+	if w := DisplayWidth(s); w < width {
+		return s + strings.Repeat(" ", width-w)
+	}
+	return s
+}
+
+func isWide(r rune) bool {
+	switch {
+	case r >= 0xFF61 && r <= 0xFF9F: // Halfwidth Katakana
+		return false
+	case r >= 0x1100 && r <= 0x115F, // Hangul Jamo
+		r >= 0x2E80 && r <= 0x303E, // CJK Radicals, Symbols and Punctuation
+		r >= 0x3041 && r <= 0x33FF, // Hiragana, Katakana and CJK Compatibility
+		r >= 0x3400 && r <= 0x4DBF, // CJK Unified Ideographs Extension A
+		r >= 0x4E00 && r <= 0x9FFF, // CJK Unified Ideographs
+		r >= 0xA000 && r <= 0xA4CF, // Yi
+		r >= 0xAC00 && r <= 0xD7A3, // Hangul Syllables
+		r >= 0xF900 && r <= 0xFAFF, // CJK Compatibility Ideographs
+		r >= 0xFE30 && r <= 0xFE4F, // CJK Compatibility Forms
+		r >= 0xFF00 && r <= 0xFF60, // Fullwidth Forms
+		r >= 0xFFE0 && r <= 0xFFE6,
+		r >= 0x20000 && r <= 0x3FFFD:
+		return true
+	}
+	return unicode.Is(unicode.Han, r)
+}